@@ -0,0 +1,5 @@
+// Package api contains the protobuf definition of the ordered map gRPC service
+// and the generated Go server and client code.
+package api
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative orderedmap.proto
@@ -0,0 +1,301 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: orderedmap.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	OrderedMap_Add_FullMethodName    = "/orderedmap.v1.OrderedMap/Add"
+	OrderedMap_Remove_FullMethodName = "/orderedmap.v1.OrderedMap/Remove"
+	OrderedMap_Get_FullMethodName    = "/orderedmap.v1.OrderedMap/Get"
+	OrderedMap_GetAll_FullMethodName = "/orderedmap.v1.OrderedMap/GetAll"
+	OrderedMap_Watch_FullMethodName  = "/orderedmap.v1.OrderedMap/Watch"
+)
+
+// OrderedMapClient is the client API for OrderedMap service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OrderedMapClient interface {
+	// Add adds the item or updates the value of the existing one in place.
+	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*AddResponse, error)
+	// Remove removes the item.
+	Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error)
+	// Get returns the value of the item.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// GetAll returns all the items in the insertion order.
+	GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (*GetAllResponse, error)
+	// Watch streams the changes of the map in the order they are applied.
+	// The watcher which lost the connection resumes after the latest change it
+	// has seen with after_seq. The stream fails with OUT_OF_RANGE if the changes
+	// are no longer kept, and with RESOURCE_EXHAUSTED if the watcher falls behind.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (OrderedMap_WatchClient, error)
+}
+
+type orderedMapClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOrderedMapClient(cc grpc.ClientConnInterface) OrderedMapClient {
+	return &orderedMapClient{cc}
+}
+
+func (c *orderedMapClient) Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*AddResponse, error) {
+	out := new(AddResponse)
+	err := c.cc.Invoke(ctx, OrderedMap_Add_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderedMapClient) Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error) {
+	out := new(RemoveResponse)
+	err := c.cc.Invoke(ctx, OrderedMap_Remove_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderedMapClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, OrderedMap_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderedMapClient) GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (*GetAllResponse, error) {
+	out := new(GetAllResponse)
+	err := c.cc.Invoke(ctx, OrderedMap_GetAll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderedMapClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (OrderedMap_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderedMap_ServiceDesc.Streams[0], OrderedMap_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderedMapWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OrderedMap_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type orderedMapWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderedMapWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OrderedMapServer is the server API for OrderedMap service.
+// All implementations must embed UnimplementedOrderedMapServer
+// for forward compatibility
+type OrderedMapServer interface {
+	// Add adds the item or updates the value of the existing one in place.
+	Add(context.Context, *AddRequest) (*AddResponse, error)
+	// Remove removes the item.
+	Remove(context.Context, *RemoveRequest) (*RemoveResponse, error)
+	// Get returns the value of the item.
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// GetAll returns all the items in the insertion order.
+	GetAll(context.Context, *GetAllRequest) (*GetAllResponse, error)
+	// Watch streams the changes of the map in the order they are applied.
+	// The watcher which lost the connection resumes after the latest change it
+	// has seen with after_seq. The stream fails with OUT_OF_RANGE if the changes
+	// are no longer kept, and with RESOURCE_EXHAUSTED if the watcher falls behind.
+	Watch(*WatchRequest, OrderedMap_WatchServer) error
+	mustEmbedUnimplementedOrderedMapServer()
+}
+
+// UnimplementedOrderedMapServer must be embedded to have forward compatible implementations.
+type UnimplementedOrderedMapServer struct {
+}
+
+func (UnimplementedOrderedMapServer) Add(context.Context, *AddRequest) (*AddResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedOrderedMapServer) Remove(context.Context, *RemoveRequest) (*RemoveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedOrderedMapServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedOrderedMapServer) GetAll(context.Context, *GetAllRequest) (*GetAllResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAll not implemented")
+}
+func (UnimplementedOrderedMapServer) Watch(*WatchRequest, OrderedMap_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedOrderedMapServer) mustEmbedUnimplementedOrderedMapServer() {}
+
+// UnsafeOrderedMapServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OrderedMapServer will
+// result in compilation errors.
+type UnsafeOrderedMapServer interface {
+	mustEmbedUnimplementedOrderedMapServer()
+}
+
+func RegisterOrderedMapServer(s grpc.ServiceRegistrar, srv OrderedMapServer) {
+	s.RegisterService(&OrderedMap_ServiceDesc, srv)
+}
+
+func _OrderedMap_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderedMapServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderedMap_Add_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderedMapServer).Add(ctx, req.(*AddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderedMap_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderedMapServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderedMap_Remove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderedMapServer).Remove(ctx, req.(*RemoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderedMap_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderedMapServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderedMap_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderedMapServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderedMap_GetAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderedMapServer).GetAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderedMap_GetAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderedMapServer).GetAll(ctx, req.(*GetAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderedMap_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderedMapServer).Watch(m, &orderedMapWatchServer{stream})
+}
+
+type OrderedMap_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type orderedMapWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderedMapWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// OrderedMap_ServiceDesc is the grpc.ServiceDesc for OrderedMap service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OrderedMap_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "orderedmap.v1.OrderedMap",
+	HandlerType: (*OrderedMapServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Add",
+			Handler:    _OrderedMap_Add_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _OrderedMap_Remove_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _OrderedMap_Get_Handler,
+		},
+		{
+			MethodName: "GetAll",
+			Handler:    _OrderedMap_GetAll_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _OrderedMap_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "orderedmap.proto",
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendRequests_InOrder(t *testing.T) {
+	broker := mq.NewMemoryBroker()
+	defer broker.Close()
+
+	reqs, err := broker.Consume()
+	require.NoError(t, err)
+
+	// Fake server which records the requests and answers them
+	received := make(chan types.Request, 3)
+	go func() {
+		for req := range reqs {
+			received <- req
+			broker.Reply(req, types.Response{})
+			broker.Ack(req)
+		}
+	}()
+
+	testData := []types.TestDataAction{
+		{RequestData: types.Request{Action: types.AddItem, Key: "k1", Value: "v1"}},
+		{RequestData: types.Request{Action: types.AddItem, Key: "k2", Value: "v2"}},
+		{RequestData: types.Request{Action: types.RemoveItem, Key: "k1"}},
+	}
+
+	err = sendRequests(broker, testData, time.Second, false)
+	require.NoError(t, err)
+
+	for _, data := range testData {
+		req := <-received
+		require.Equal(t, data.RequestData.Action, req.Action)
+		require.Equal(t, data.RequestData.Key, req.Key)
+		require.Equal(t, data.RequestData.Value, req.Value)
+		require.NotEmpty(t, req.ID)
+	}
+}
+
+func TestSendRequests_Timeout(t *testing.T) {
+	broker := mq.NewMemoryBroker()
+	defer broker.Close()
+
+	testData := []types.TestDataAction{
+		{RequestData: types.Request{Action: types.GetItem, Key: "k1"}},
+	}
+
+	err := sendRequests(broker, testData, 10*time.Millisecond, false)
+	require.ErrorIs(t, err, mq.ErrTimeout)
+}
@@ -8,7 +8,9 @@ import (
 	types "github.com/enriquenc/orderer-map-client-server-go/shared"
 )
 
-func parseRequestData(fileName, action, key, value string) ([]types.TestDataAction, error) {
+// parseRequestData reads the actions from the file or, if there is no file,
+// validates the one request set by the command line arguments.
+func parseRequestData(fileName string, req types.Request) ([]types.TestDataAction, error) {
 	var testData []types.TestDataAction
 
 	if fileName != "" {
@@ -17,11 +19,11 @@ func parseRequestData(fileName, action, key, value string) ([]types.TestDataActi
 			return nil, fmt.Errorf("Error in file passing: %v", err)
 		}
 	} else {
-		err := validateCommandLineArguments(action, key, value)
+		err := validateCommandLineArguments(req)
 		if err != nil {
 			return nil, fmt.Errorf("Error in arguments parsing: %v", err)
 		}
-		testData = append(testData, types.TestDataAction{RequestData: types.Request{Key: key, Value: value, Action: action}})
+		testData = append(testData, types.TestDataAction{RequestData: req})
 	}
 
 	return testData, nil
@@ -50,25 +52,134 @@ func parseDataFromFile(testData *[]types.TestDataAction, fileName string) error
 	return nil
 }
 
-func validateCommandLineArguments(action, key, value string) error {
+func validateCommandLineArguments(req types.Request) error {
+	action := req.Action
 	// Validate command line arguments
 	if action == "" || !isValidAction(action) {
-		return fmt.Errorf("Invalid action. Must be one of: %s, %s, %s, %s.", types.AddItem, types.GetItem, types.RemoveItem, types.GetAll)
+		return fmt.Errorf("Invalid action. Must be one of: %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s.",
+			types.AddItem, types.GetItem, types.RemoveItem, types.GetAll,
+			types.InsertBefore, types.InsertAfter, types.MoveToFront, types.MoveToBack,
+			types.GetPage, types.GetHead, types.GetTail, types.GetAt, types.IndexOf,
+			types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap, types.RemoveIfValue, types.Batch)
 	}
-	if (action == types.AddItem || action == types.GetItem || action == types.RemoveItem) && key == "" {
-		return fmt.Errorf("Key is required for %s, %s, and %s actions.", types.AddItem, types.GetItem, types.RemoveItem)
+	if action == types.Batch {
+		return validateBatch(req)
 	}
-	if (action == types.AddItem) && value == "" {
-		return fmt.Errorf("Value is required for %s action.", types.AddItem)
+	if len(req.Ops) > 0 {
+		return fmt.Errorf("Ops are only allowed for %s action.", types.Batch)
+	}
+	if !isReadAllAction(action) && req.Key == "" {
+		return fmt.Errorf("Key is required for all the actions except %s, %s, %s, %s, %s, and %s.", types.GetAll, types.GetPage, types.GetHead, types.GetTail, types.GetAt, types.Batch)
+	}
+	if isAddingAction(action) && req.Value == "" {
+		return fmt.Errorf("Value is required for %s, %s, %s, %s, %s, and %s actions.", types.AddItem, types.InsertBefore, types.InsertAfter,
+			types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap)
+	}
+	if action == types.CompareAndSwap || action == types.RemoveIfValue {
+		if req.Expected == "" && req.Version == 0 {
+			return fmt.Errorf("Expected value or version is required for %s and %s actions.", types.CompareAndSwap, types.RemoveIfValue)
+		}
+	} else if req.Expected != "" || req.Version != 0 {
+		return fmt.Errorf("Expected value and version are only allowed for %s and %s actions.", types.CompareAndSwap, types.RemoveIfValue)
+	}
+	if action == types.InsertBefore || action == types.InsertAfter {
+		if req.Mark == "" {
+			return fmt.Errorf("Mark is required for %s and %s actions.", types.InsertBefore, types.InsertAfter)
+		}
+		if req.Mark == req.Key {
+			return fmt.Errorf("Mark must differ from the key.")
+		}
+	} else if req.Mark != "" {
+		return fmt.Errorf("Mark is only allowed for %s and %s actions.", types.InsertBefore, types.InsertAfter)
+	}
+	if req.TTL < 0 {
+		return fmt.Errorf("TTL must be positive.")
+	}
+	if req.TTL != 0 && !isAddingAction(action) {
+		return fmt.Errorf("TTL is only allowed for %s, %s, %s, %s, %s, and %s actions.", types.AddItem, types.InsertBefore, types.InsertAfter,
+			types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap)
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		return fmt.Errorf("Offset and limit must not be negative.")
+	}
+	if (action == types.GetHead || action == types.GetTail) && req.Limit == 0 {
+		return fmt.Errorf("Limit is required for %s and %s actions.", types.GetHead, types.GetTail)
+	}
+	if req.Limit != 0 && !isPageAction(action) {
+		return fmt.Errorf("Limit is only allowed for %s, %s, and %s actions.", types.GetPage, types.GetHead, types.GetTail)
+	}
+	if req.Offset != 0 && action != types.GetPage && action != types.GetAt {
+		return fmt.Errorf("Offset is only allowed for %s and %s actions.", types.GetPage, types.GetAt)
+	}
+	if (req.After != "" || req.Reverse) && action != types.GetPage {
+		return fmt.Errorf("After and reverse are only allowed for %s action.", types.GetPage)
+	}
+	if req.Offset != 0 && req.After != "" {
+		return fmt.Errorf("Offset and after are exclusive.")
+	}
+	if req.Revision != 0 && action != types.GetItem && action != types.GetAll && !isPageAction(action) {
+		return fmt.Errorf("Revision is only allowed for %s, %s, %s, %s, and %s actions.", types.GetItem, types.GetAll, types.GetPage, types.GetHead, types.GetTail)
+	}
+	return nil
+}
+
+// validateBatch checks the batch request and every operation of it.
+func validateBatch(req types.Request) error {
+	if req.Key != "" || req.Value != "" || req.Mark != "" || req.TTL != 0 ||
+		req.Offset != 0 || req.Limit != 0 || req.After != "" || req.Reverse || req.Expected != "" || req.Version != 0 || req.Revision != 0 {
+		return fmt.Errorf("The %s action takes the ops only.", types.Batch)
+	}
+	if len(req.Ops) == 0 {
+		return fmt.Errorf("Ops are required for %s action.", types.Batch)
+	}
+	for i, op := range req.Ops {
+		if !isMutationAction(op.Action) {
+			return fmt.Errorf("Operation %d: action %q isn't allowed in a batch, only %s, %s, %s, %s, %s, and %s are.", i, op.Action,
+				types.AddItem, types.RemoveItem, types.InsertBefore, types.InsertAfter, types.MoveToFront, types.MoveToBack)
+		}
+		if err := validateCommandLineArguments(op); err != nil {
+			return fmt.Errorf("Operation %d: %v", i, err)
+		}
 	}
 	return nil
 }
 
 func isValidAction(action string) bool {
 	switch action {
-	case types.AddItem, types.RemoveItem, types.GetItem, types.GetAll:
+	case types.AddItem, types.RemoveItem, types.GetItem, types.GetAll,
+		types.InsertBefore, types.InsertAfter, types.MoveToFront, types.MoveToBack,
+		types.GetPage, types.GetHead, types.GetTail, types.GetAt, types.IndexOf,
+		types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap, types.RemoveIfValue, types.Batch:
 		return true
 	default:
 		return false
 	}
 }
+
+// isAddingAction reports whether the action adds or updates the item with the value.
+func isAddingAction(action string) bool {
+	switch action {
+	case types.AddItem, types.InsertBefore, types.InsertAfter, types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap:
+		return true
+	}
+	return false
+}
+
+// isMutationAction reports whether the action changes the map, only these are allowed in a batch.
+func isMutationAction(action string) bool {
+	switch action {
+	case types.AddItem, types.RemoveItem, types.InsertBefore, types.InsertAfter, types.MoveToFront, types.MoveToBack:
+		return true
+	}
+	return false
+}
+
+// isPageAction reports whether the action reads a page of the items.
+func isPageAction(action string) bool {
+	return action == types.GetPage || action == types.GetHead || action == types.GetTail
+}
+
+// isReadAllAction reports whether the action reads the items without a key.
+func isReadAllAction(action string) bool {
+	return action == types.GetAll || action == types.GetAt || isPageAction(action)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/enriquenc/orderer-map-client-server-go/shared"
 	types "github.com/enriquenc/orderer-map-client-server-go/shared"
@@ -78,7 +79,7 @@ func TestMain_InvalidJSONFile(t *testing.T) {
 	require.NoError(t, err)
 
 	// Call function under test
-	_, err = parseRequestData(file.Name(), "", "", "")
+	_, err = parseRequestData(file.Name(), types.Request{})
 
 	// Assert error is returned with expected message
 	require.Error(t, err)
@@ -93,7 +94,7 @@ func TestParseRequestData_ValidArgs(t *testing.T) {
 	value := "bar"
 
 	// Call parseRequestData
-	testData, err := parseRequestData(fileName, action, key, value)
+	testData, err := parseRequestData(fileName, types.Request{Action: action, Key: key, Value: value})
 
 	// Assert that no error occurred
 	if err != nil {
@@ -121,7 +122,7 @@ func TestParseRequestDataWithInvalidArgs(t *testing.T) {
 	value := ""
 
 	// Call parseRequestData with invalid arguments
-	_, err := parseRequestData(fileName, action, key, value)
+	_, err := parseRequestData(fileName, types.Request{Action: action, Key: key, Value: value})
 
 	// Check if an error was returned
 	if err == nil {
@@ -129,8 +130,145 @@ func TestParseRequestDataWithInvalidArgs(t *testing.T) {
 	}
 }
 
+func TestParseRequestData_TTL(t *testing.T) {
+	testData, err := parseRequestData("", types.Request{Action: types.AddItem, Key: "foo", Value: "bar", TTL: time.Minute})
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, testData[0].RequestData.TTL)
+
+	_, err = parseRequestData("", types.Request{Action: types.GetItem, Key: "foo", TTL: time.Minute})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "TTL is only allowed")
+
+	_, err = parseRequestData("", types.Request{Action: types.AddItem, Key: "foo", Value: "bar", TTL: -time.Minute})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "TTL must be positive")
+}
+
+func TestParseRequestData_Positional(t *testing.T) {
+	testData, err := parseRequestData("", types.Request{Action: types.InsertBefore, Key: "foo", Value: "bar", Mark: "baz"})
+	require.NoError(t, err)
+	require.Equal(t, types.Request{Action: types.InsertBefore, Key: "foo", Value: "bar", Mark: "baz"}, testData[0].RequestData)
+
+	_, err = parseRequestData("", types.Request{Action: types.MoveToFront, Key: "foo"})
+	require.NoError(t, err)
+
+	for _, args := range [][3]string{
+		{types.InsertAfter, "foo", ""},
+		{types.InsertAfter, "foo", "foo"},
+		{types.MoveToBack, "foo", "baz"},
+		{types.MoveToBack, "", ""},
+	} {
+		_, err = parseRequestData("", types.Request{Action: args[0], Key: args[1], Value: "bar", Mark: args[2]})
+		require.Error(t, err, "action %s with key %q and mark %q", args[0], args[1], args[2])
+	}
+}
+
+func TestParseRequestData_Page(t *testing.T) {
+	valid := []types.Request{
+		{Action: types.GetPage},
+		{Action: types.GetPage, Offset: 10, Limit: 5, Reverse: true},
+		{Action: types.GetPage, After: "foo", Limit: 5},
+		{Action: types.GetHead, Limit: 5},
+		{Action: types.GetTail, Limit: 5},
+		{Action: types.GetAt, Offset: 5},
+		{Action: types.IndexOf, Key: "foo"},
+	}
+	for _, req := range valid {
+		_, err := parseRequestData("", req)
+		require.NoError(t, err, "%+v", req)
+	}
+
+	invalid := []types.Request{
+		{Action: types.GetPage, After: "foo", Offset: 1},
+		{Action: types.GetPage, Limit: -1},
+		{Action: types.GetHead},
+		{Action: types.GetTail, Limit: 5, Reverse: true},
+		{Action: types.GetAll, Limit: 5},
+		{Action: types.GetAt, Reverse: true},
+		{Action: types.IndexOf},
+	}
+	for _, req := range invalid {
+		_, err := parseRequestData("", req)
+		require.Error(t, err, "%+v", req)
+	}
+}
+
+func TestParseRequestData_Conditional(t *testing.T) {
+	valid := []types.Request{
+		{Action: types.AddIfAbsent, Key: "foo", Value: "bar", TTL: time.Minute},
+		{Action: types.UpdateIfPresent, Key: "foo", Value: "bar"},
+		{Action: types.CompareAndSwap, Key: "foo", Value: "bar", Expected: "baz"},
+		{Action: types.CompareAndSwap, Key: "foo", Value: "bar", Version: 2},
+		{Action: types.RemoveIfValue, Key: "foo", Expected: "baz"},
+	}
+	for _, req := range valid {
+		_, err := parseRequestData("", req)
+		require.NoError(t, err, "%+v", req)
+	}
+
+	invalid := []types.Request{
+		{Action: types.AddIfAbsent, Key: "foo"},
+		{Action: types.UpdateIfPresent, Key: "foo", Value: "bar", Expected: "baz"},
+		{Action: types.CompareAndSwap, Key: "foo", Value: "bar"},
+		{Action: types.RemoveIfValue, Key: "foo"},
+		{Action: types.RemoveIfValue, Key: "foo", Expected: "baz", TTL: time.Minute},
+		{Action: types.AddItem, Key: "foo", Value: "bar", Version: 1},
+		{Action: types.Batch, Ops: []types.Request{{Action: types.AddIfAbsent, Key: "foo", Value: "bar"}}},
+	}
+	for _, req := range invalid {
+		_, err := parseRequestData("", req)
+		require.Error(t, err, "%+v", req)
+	}
+}
+
+func TestParseRequestData_Revision(t *testing.T) {
+	for _, action := range []string{types.GetAll, types.GetPage} {
+		testData, err := parseRequestData("", types.Request{Action: action, Revision: 5})
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), testData[0].RequestData.Revision)
+	}
+	_, err := parseRequestData("", types.Request{Action: types.GetItem, Key: "foo", Revision: 5})
+	require.NoError(t, err)
+
+	_, err = parseRequestData("", types.Request{Action: types.AddItem, Key: "foo", Value: "bar", Revision: 5})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Revision is only allowed")
+	_, err = parseRequestData("", types.Request{Action: types.IndexOf, Key: "foo", Revision: 5})
+	require.Error(t, err)
+}
+
+func TestParseRequestData_Batch(t *testing.T) {
+	ops := []types.Request{
+		{Action: types.AddItem, Key: "foo", Value: "bar", TTL: time.Minute},
+		{Action: types.InsertAfter, Key: "baz", Value: "qux", Mark: "foo"},
+		{Action: types.RemoveItem, Key: "quux"},
+	}
+	testData, err := parseRequestData("", types.Request{Action: types.Batch, Ops: ops})
+	require.NoError(t, err)
+	require.Equal(t, ops, testData[0].RequestData.Ops)
+
+	invalid := []types.Request{
+		{Action: types.Batch},
+		{Action: types.Batch, Key: "foo", Ops: ops},
+		{Action: types.Batch, Ops: []types.Request{{Action: types.GetItem, Key: "foo"}}},
+		{Action: types.Batch, Ops: []types.Request{{Action: types.Batch, Ops: ops}}},
+		{Action: types.Batch, Ops: []types.Request{{Action: types.AddItem, Key: "foo"}}},
+		{Action: types.AddItem, Key: "foo", Value: "bar", Ops: ops},
+	}
+	for _, req := range invalid {
+		_, err := parseRequestData("", req)
+		require.Error(t, err, "%+v", req)
+	}
+
+	_, err = parseRequestData("", types.Request{Action: types.Batch, Ops: []types.Request{ops[0], {Action: types.MoveToBack}}})
+	require.EqualError(t, err, "Error in arguments parsing: Operation 1: Key is required for all the actions except getAll, getPage, getHead, getTail, getAt, and batch.")
+}
+
 func TestIsValidActionWithValidActions(t *testing.T) {
-	validActions := []string{types.AddItem, types.GetItem, types.RemoveItem, types.GetAll}
+	validActions := []string{types.AddItem, types.GetItem, types.RemoveItem, types.GetAll,
+		types.InsertBefore, types.InsertAfter, types.MoveToFront, types.MoveToBack,
+		types.GetPage, types.GetHead, types.GetTail, types.GetAt, types.IndexOf,
+		types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap, types.RemoveIfValue, types.Batch}
 
 	for _, action := range validActions {
 		if !isValidAction(action) {
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// verifyRequests sends the test data actions one by one and compares every server
+// response with the expected one. Every mismatch is reported to out.
+// It returns the number of mismatches found.
+//
+// The expected responses are computed from an empty map, so the server should be
+// started from scratch and no other client should write to the same queue.
+func verifyRequests(broker mq.Broker, testData []types.TestDataAction, timeout time.Duration, out io.Writer) (int, error) {
+	mismatches := 0
+
+	for i := 0; i < len(testData); i++ {
+		req := testData[i].RequestData
+
+		resp, err := broker.Call(withRequestID(req), timeout)
+		if err != nil {
+			return mismatches, fmt.Errorf("action %d: %v", i, err)
+		}
+
+		expected, actual, ok := compareResponse(req, testData[i].ExpectedResponse, resp)
+		if !ok {
+			mismatches++
+			reqData, _ := json.Marshal(req)
+			fmt.Fprintf(out, "Mismatch at action %d %s: expected %s, got %s\n", i, reqData, expected, actual)
+		}
+	}
+
+	return mismatches, nil
+}
+
+// compareResponse checks the response against the expected one and returns
+// both of them in the test data format.
+func compareResponse(req types.Request, expectedResponse json.RawMessage, resp types.Response) (string, string, bool) {
+	expected := "null"
+	if len(bytes.TrimSpace(expectedResponse)) > 0 {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, expectedResponse); err != nil {
+			return string(expectedResponse), "invalid expected response", false
+		}
+		expected = buf.String()
+	}
+
+	if resp.Error != "" {
+		return expected, fmt.Sprintf("error %q", resp.Error), false
+	}
+
+	var actual []byte
+	switch req.Action {
+	case types.GetItem:
+		if resp.Found {
+			actual, _ = json.Marshal(resp.Value)
+		} else {
+			actual = []byte("null")
+		}
+	case types.GetAll, types.GetPage, types.GetHead, types.GetTail:
+		actual, _ = json.Marshal(resp.Items)
+	default:
+		// Mutations have no expected response
+		actual = []byte("null")
+	}
+
+	return expected, string(actual), expected == string(actual)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      types.Request
+		expected string
+		resp     types.Response
+		ok       bool
+	}{
+		{"add", types.Request{Action: types.AddItem}, `null`, types.Response{}, true},
+		{"add error", types.Request{Action: types.AddItem}, `null`, types.Response{Error: "failed"}, false},
+		{"get found", types.Request{Action: types.GetItem}, `"v1"`, types.Response{Found: true, Value: "v1"}, true},
+		{"get not found", types.Request{Action: types.GetItem}, `null`, types.Response{}, true},
+		{"get wrong value", types.Request{Action: types.GetItem}, `"v1"`, types.Response{Found: true, Value: "v2"}, false},
+		{"get unexpected", types.Request{Action: types.GetItem}, `null`, types.Response{Found: true, Value: "v1"}, false},
+		{"getAll", types.Request{Action: types.GetAll}, `{"k2": "v2", "k1": "v1"}`,
+			types.Response{Items: types.Items{{Key: "k2", Value: "v2"}, {Key: "k1", Value: "v1"}}}, true},
+		{"getAll wrong order", types.Request{Action: types.GetAll}, `{"k2":"v2","k1":"v1"}`,
+			types.Response{Items: types.Items{{Key: "k1", Value: "v1"}, {Key: "k2", Value: "v2"}}}, false},
+		{"getAll empty", types.Request{Action: types.GetAll}, `{}`, types.Response{}, true},
+	}
+
+	for _, test := range tests {
+		_, _, ok := compareResponse(test.req, json.RawMessage(test.expected), test.resp)
+		if ok != test.ok {
+			t.Errorf("%s: compareResponse() = %v, expected %v", test.name, ok, test.ok)
+		}
+	}
+}
+
+func TestVerifyRequests_ReportsMismatches(t *testing.T) {
+	broker := mq.NewMemoryBroker()
+	defer broker.Close()
+
+	reqs, err := broker.Consume()
+	require.NoError(t, err)
+
+	// Fake server which never finds anything
+	go func() {
+		for req := range reqs {
+			broker.Reply(req, types.Response{})
+			broker.Ack(req)
+		}
+	}()
+
+	testData := []types.TestDataAction{
+		{RequestData: types.Request{Action: types.AddItem, Key: "k1", Value: "v1"}, ExpectedResponse: json.RawMessage(`null`)},
+		{RequestData: types.Request{Action: types.GetItem, Key: "k1"}, ExpectedResponse: json.RawMessage(`"v1"`)},
+		{RequestData: types.Request{Action: types.GetItem, Key: "k2"}, ExpectedResponse: json.RawMessage(`null`)},
+	}
+
+	var out bytes.Buffer
+	mismatches, err := verifyRequests(broker, testData, time.Second, &out)
+	require.NoError(t, err)
+	require.Equal(t, 1, mismatches)
+	require.Equal(t, "Mismatch at action 1 {\"Action\":\"get\",\"Key\":\"k1\",\"Value\":\"\"}: expected \"v1\", got null\n", out.String())
+}
@@ -0,0 +1,41 @@
+package rabbitmq
+
+import (
+	"errors"
+	"time"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// ErrClosed is returned when the broker is used after Close.
+var ErrClosed = errors.New("broker is closed")
+
+// Broker is a message queue used to pass the requests from the clients
+// to the server and the responses back. Requests are delivered in the order
+// they were published.
+//
+// Every consumed request has to be settled by Ack or Nack. The next request
+// isn't delivered to the consumer until then, so a requeued request is
+// delivered again before the following ones and the processing order is kept.
+type Broker interface {
+	// Publish sends the request to the requests queue.
+	Publish(req types.Request) error
+	// Consume starts delivering the published requests to the returned channel.
+	Consume() (<-chan types.Request, error)
+	// Ack acknowledges that the consumed request has been processed.
+	Ack(req types.Request) error
+	// Nack rejects the consumed request. The request is returned to the head
+	// of the queue if requeue is set and dropped otherwise.
+	Nack(req types.Request, requeue bool) error
+	// Reply sends the response to the sender of the consumed request.
+	Reply(req types.Request, resp types.Response) error
+	// Call publishes the request and waits for the reply to it.
+	Call(req types.Request, timeout time.Duration) (types.Response, error)
+	// Close releases the broker resources.
+	Close() error
+}
+
+var (
+	_ Broker = (*RabbitMQ)(nil)
+	_ Broker = (*MemoryBroker)(nil)
+)
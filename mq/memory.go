@@ -0,0 +1,214 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// memoryReplyQueue is the reply queue name set to the requests sent by Call.
+const memoryReplyQueue = "memory.reply"
+
+// MemoryBroker is an in-process Broker. It keeps the requests in an unbounded
+// FIFO queue and delivers each of them to exactly one consumer in the publish
+// order, the same way a single RabbitMQ queue does. A consumer gets the next
+// request only after the previous one is acknowledged or rejected.
+type MemoryBroker struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	requests []types.Request
+	pending  map[string]chan types.Response
+	closed   bool
+	done     chan struct{}
+
+	// Delivered but not settled requests by the delivery tag
+	lastTag uint64
+	unacked map[uint64]*memoryConsumer
+}
+
+type memoryConsumer struct {
+	unacked int
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	b := &MemoryBroker{
+		pending: make(map[string]chan types.Response),
+		done:    make(chan struct{}),
+		unacked: make(map[uint64]*memoryConsumer),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	b.closed = true
+	close(b.done)
+	b.cond.Broadcast()
+
+	return nil
+}
+
+func (b *MemoryBroker) Publish(req types.Request) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return fmt.Errorf("failed to publish message: %w", ErrClosed)
+	}
+	b.requests = append(b.requests, req)
+	b.cond.Broadcast()
+
+	return nil
+}
+
+func (b *MemoryBroker) Consume() (<-chan types.Request, error) {
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed {
+		return nil, fmt.Errorf("failed to consume messages: %w", ErrClosed)
+	}
+
+	requests := make(chan types.Request)
+	consumer := &memoryConsumer{}
+
+	go func() {
+		defer close(requests)
+		for {
+			req, ok := b.next(consumer)
+			if !ok {
+				return
+			}
+			select {
+			case requests <- req:
+			case <-b.done:
+				return
+			}
+		}
+	}()
+
+	return requests, nil
+}
+
+// next blocks until there is a request in the queue and the previous request
+// delivered to the consumer is settled, or the broker is closed.
+func (b *MemoryBroker) next(consumer *memoryConsumer) (types.Request, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for (len(b.requests) == 0 || consumer.unacked >= prefetchCount) && !b.closed {
+		b.cond.Wait()
+	}
+	if b.closed {
+		return types.Request{}, false
+	}
+
+	req := b.requests[0]
+	b.requests[0] = types.Request{}
+	b.requests = b.requests[1:]
+
+	b.lastTag++
+	req.DeliveryTag = b.lastTag
+	b.unacked[req.DeliveryTag] = consumer
+	consumer.unacked++
+
+	return req, true
+}
+
+func (b *MemoryBroker) Ack(req types.Request) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.settle(req)
+}
+
+func (b *MemoryBroker) Nack(req types.Request, requeue bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.settle(req); err != nil {
+		return err
+	}
+	if requeue {
+		req.DeliveryTag = 0
+		b.requests = append([]types.Request{req}, b.requests...)
+	}
+
+	return nil
+}
+
+// settle removes the request from the unacknowledged ones.
+func (b *MemoryBroker) settle(req types.Request) error {
+	consumer, ok := b.unacked[req.DeliveryTag]
+	if !ok {
+		return fmt.Errorf("unknown delivery tag %d", req.DeliveryTag)
+	}
+	delete(b.unacked, req.DeliveryTag)
+	consumer.unacked--
+	b.cond.Broadcast()
+
+	return nil
+}
+
+func (b *MemoryBroker) Reply(req types.Request, resp types.Response) error {
+	if req.ReplyTo != memoryReplyQueue {
+		return nil
+	}
+
+	b.mu.Lock()
+	replyChan, ok := b.pending[req.CorrelationID]
+	b.mu.Unlock()
+	// Replies to the timed out calls and duplicates are dropped
+	if ok {
+		select {
+		case replyChan <- resp:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Call(req types.Request, timeout time.Duration) (types.Response, error) {
+	correlationID, err := newCorrelationID()
+	if err != nil {
+		return types.Response{}, err
+	}
+
+	replyChan := make(chan types.Response, 1)
+	b.mu.Lock()
+	b.pending[correlationID] = replyChan
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, correlationID)
+		b.mu.Unlock()
+	}()
+
+	req.ReplyTo = memoryReplyQueue
+	req.CorrelationID = correlationID
+	if err := b.Publish(req); err != nil {
+		return types.Response{}, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-replyChan:
+		return resp, nil
+	case <-timer.C:
+		return types.Response{}, ErrTimeout
+	case <-b.done:
+		return types.Response{}, ErrClosed
+	}
+}
@@ -0,0 +1,142 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBroker_Close(t *testing.T) {
+	b := NewMemoryBroker()
+
+	err := b.Close()
+	assert.NoError(t, err)
+
+	err = b.Close()
+	assert.Error(t, err)
+
+	err = b.Publish(types.Request{Key: "k1", Value: "v1", Action: "add"})
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestMemoryBroker_ConsumeInOrder(t *testing.T) {
+	b := NewMemoryBroker()
+	defer b.Close()
+
+	// Publish before consuming, the requests must wait in the queue
+	for i := 0; i < 100; i++ {
+		err := b.Publish(types.Request{Key: fmt.Sprintf("k%d", i), Action: "add"})
+		assert.NoError(t, err)
+	}
+
+	ch, err := b.Consume()
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		req := <-ch
+		assert.Equal(t, fmt.Sprintf("k%d", i), req.Key)
+		assert.NoError(t, b.Ack(req))
+	}
+}
+
+func TestMemoryBroker_WaitsForAck(t *testing.T) {
+	b := NewMemoryBroker()
+	defer b.Close()
+
+	ch, err := b.Consume()
+	assert.NoError(t, err)
+
+	assert.NoError(t, b.Publish(types.Request{Key: "k1", Action: "add"}))
+	assert.NoError(t, b.Publish(types.Request{Key: "k2", Action: "add"}))
+
+	req := <-ch
+	assert.Equal(t, "k1", req.Key)
+
+	// The next request isn't delivered until the previous is settled
+	select {
+	case req := <-ch:
+		t.Fatalf("Unexpected delivery before ack: %v", req)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, b.Ack(req))
+	req = <-ch
+	assert.Equal(t, "k2", req.Key)
+
+	// Double ack is an error
+	assert.NoError(t, b.Ack(req))
+	assert.Error(t, b.Ack(req))
+}
+
+func TestMemoryBroker_NackRequeue(t *testing.T) {
+	b := NewMemoryBroker()
+	defer b.Close()
+
+	ch, err := b.Consume()
+	assert.NoError(t, err)
+
+	for _, key := range []string{"k1", "k2", "k3"} {
+		assert.NoError(t, b.Publish(types.Request{Key: key, Action: "add"}))
+	}
+
+	// The requeued request is delivered again before the following ones
+	req := <-ch
+	assert.Equal(t, "k1", req.Key)
+	assert.NoError(t, b.Nack(req, true))
+
+	req = <-ch
+	assert.Equal(t, "k1", req.Key)
+	assert.NoError(t, b.Ack(req))
+
+	// The rejected request without requeue is dropped
+	req = <-ch
+	assert.Equal(t, "k2", req.Key)
+	assert.NoError(t, b.Nack(req, false))
+
+	req = <-ch
+	assert.Equal(t, "k3", req.Key)
+	assert.NoError(t, b.Ack(req))
+}
+
+func TestMemoryBroker_ConsumeClosed(t *testing.T) {
+	b := NewMemoryBroker()
+
+	ch, err := b.Consume()
+	assert.NoError(t, err)
+
+	b.Close()
+
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestMemoryBroker_Call(t *testing.T) {
+	b := NewMemoryBroker()
+	defer b.Close()
+
+	ch, err := b.Consume()
+	assert.NoError(t, err)
+
+	go func() {
+		for req := range ch {
+			err := b.Reply(req, types.Response{Found: true, Value: req.Value})
+			assert.NoError(t, err)
+			assert.NoError(t, b.Ack(req))
+		}
+	}()
+
+	resp, err := b.Call(types.Request{Key: "k1", Value: "v1", Action: "get"}, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Response{Found: true, Value: "v1"}, resp)
+}
+
+func TestMemoryBroker_CallTimeout(t *testing.T) {
+	b := NewMemoryBroker()
+	defer b.Close()
+
+	_, err := b.Call(types.Request{Key: "k1", Action: "get"}, 10*time.Millisecond)
+	assert.ErrorIs(t, err, ErrTimeout)
+}
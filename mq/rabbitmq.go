@@ -3,94 +3,228 @@
 package rabbitmq
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	types "github.com/enriquenc/orderer-map-client-server-go/shared"
 	"github.com/streadway/amqp"
 )
 
+// ErrTimeout is returned by Call when no reply arrives in time.
+var ErrTimeout = errors.New("timed out waiting for reply")
+
+// prefetchCount is the number of unacknowledged requests delivered to the consumer.
+// It must be 1 to keep the order, otherwise the requeued request is processed
+// after the requests delivered before its rejection.
+const prefetchCount = 1
+
+// RabbitMQ is the Broker backed by a RabbitMQ queue. The connection is supervised:
+// if it's lost, RabbitMQ reconnects with backoff, declares the queue again and
+// resumes consuming to the same channel returned by Consume. The messages published
+// while disconnected are handled according to the PublishPolicy.
 type RabbitMQ struct {
+	url       string
+	queueName string
+	opts      options
+
+	mu      sync.Mutex
 	conn    *amqp.Connection
 	channel *amqp.Channel
 	queue   amqp.Queue
+	// generation is incremented on every reconnect attempt to detect
+	// the delivery tags of the closed channels
+	generation uint64
+	connected  bool
+	closed     bool
+	// requests is the channel returned by Consume, nil if not consuming
+	requests  chan types.Request
+	consumers sync.WaitGroup
+	// buffer keeps the messages published while disconnected
+	buffer []publishing
+
+	// Reply queue state used by Call. The queue is declared on the first call.
+	replyMu    sync.Mutex
+	replyQueue string
+	pending    map[string]chan types.Response
+
+	events chan ConnectionEvent
+	stats  stats
+	done   chan struct{}
 }
 
-func NewMQ(rabbitMQURL, queueName string) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(rabbitMQURL)
+// publishing is a message waiting to be published.
+type publishing struct {
+	routingKey string
+	msg        amqp.Publishing
+}
+
+func NewMQ(rabbitMQURL, queueName string, opts ...Option) (*RabbitMQ, error) {
+	r := &RabbitMQ{
+		url:       rabbitMQURL,
+		queueName: queueName,
+		opts:      defaultOptions,
+		pending:   make(map[string]chan types.Response),
+		events:    make(chan ConnectionEvent, eventsBufferSize),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+
+	conn, ch, q, err := r.connect()
+	if err != nil {
+		return nil, err
+	}
+	r.conn = conn
+	r.channel = ch
+	r.queue = q
+	r.connected = true
+	r.emit(ConnectionEvent{State: Connected})
+
+	go r.supervise(conn, ch)
+
+	return r, nil
+}
+
+// connect dials RabbitMQ, opens a channel and declares the requests queue.
+func (r *RabbitMQ) connect() (*amqp.Connection, *amqp.Channel, amqp.Queue, error) {
+	conn, err := amqp.Dial(r.url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %v", err)
+		return nil, nil, amqp.Queue{}, fmt.Errorf("failed to connect to RabbitMQ: %v", err)
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open a channel: %v", err)
+		conn.Close()
+		return nil, nil, amqp.Queue{}, fmt.Errorf("failed to open a channel: %v", err)
 	}
 
 	q, err := ch.QueueDeclare(
-		queueName, // name
-		false,     // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
+		r.queueName, // name
+		false,       // durable
+		false,       // delete when unused
+		false,       // exclusive
+		false,       // no-wait
+		nil,         // arguments
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare a queue: %v", err)
+		conn.Close()
+		return nil, nil, amqp.Queue{}, fmt.Errorf("failed to declare a queue: %v", err)
 	}
 
-	return &RabbitMQ{
-		conn:    conn,
-		channel: ch,
-		queue:   q,
-	}, nil
+	return conn, ch, q, nil
 }
 
 func (r *RabbitMQ) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrClosed
+	}
+	r.closed = true
+	r.connected = false
+	close(r.done)
+
+	var err error
 	if r.channel != nil {
-		if err := r.channel.Close(); err != nil {
-			return fmt.Errorf("failed to close RabbitMQ channel: %v", err)
+		if closeErr := r.channel.Close(); closeErr != nil && closeErr != amqp.ErrClosed {
+			err = fmt.Errorf("failed to close RabbitMQ channel: %v", closeErr)
 		}
 	}
-
 	if r.conn != nil {
-		if err := r.conn.Close(); err != nil {
-			return fmt.Errorf("failed to close RabbitMQ connection: %v", err)
+		if closeErr := r.conn.Close(); closeErr != nil && closeErr != amqp.ErrClosed && err == nil {
+			err = fmt.Errorf("failed to close RabbitMQ connection: %v", closeErr)
 		}
 	}
+	requests := r.requests
+	r.mu.Unlock()
 
-	return nil
+	// The requests channel is closed when no consumer can write to it anymore
+	r.consumers.Wait()
+	if requests != nil {
+		close(requests)
+	}
+	r.emit(ConnectionEvent{State: Closed})
+
+	return err
 }
 
 func (r *RabbitMQ) Consume() (<-chan types.Request, error) {
-	msgs, err := r.channel.Consume(
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, fmt.Errorf("failed to consume messages: %w", ErrClosed)
+	}
+	if r.requests != nil {
+		return nil, errors.New("failed to consume messages: already consuming")
+	}
+	if !r.connected {
+		return nil, fmt.Errorf("failed to consume messages: %w", ErrNotConnected)
+	}
+
+	requests := make(chan types.Request)
+	if err := r.startConsumer(r.channel, r.generation, requests); err != nil {
+		return nil, err
+	}
+	r.requests = requests
+
+	return requests, nil
+}
+
+// startConsumer consumes the requests queue on the channel and forwards the
+// decoded requests to the requests channel until the RabbitMQ channel is closed.
+func (r *RabbitMQ) startConsumer(ch *amqp.Channel, generation uint64, requests chan<- types.Request) error {
+	if err := ch.Qos(prefetchCount, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch count: %v", err)
+	}
+
+	msgs, err := ch.Consume(
 		r.queue.Name, // queue
 		"",           // consumer
-		true,         // auto-ack
+		false,        // auto-ack
 		false,        // exclusive
 		false,        // no-local
 		false,        // no-wait
 		nil,          // args
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to consume messages: %v", err)
+		return fmt.Errorf("failed to consume messages: %v", err)
 	}
 
-	requests := make(chan types.Request)
-
+	r.consumers.Add(1)
 	go func() {
+		defer r.consumers.Done()
+		// The deliveries channel is closed with the RabbitMQ channel
 		for msg := range msgs {
 			var req types.Request
 			if err := json.Unmarshal(msg.Body, &req); err != nil {
 				log.Printf("failed to decode message: %v", err)
+				// The message can't be processed ever, drop it to not block the queue
+				if err := msg.Nack(false, false); err != nil {
+					log.Printf("failed to reject message: %v", err)
+				}
 				continue
 			}
-			requests <- req
+			req.ReplyTo = msg.ReplyTo
+			req.CorrelationID = msg.CorrelationId
+			req.DeliveryTag = encodeDeliveryTag(generation, msg.DeliveryTag)
+
+			select {
+			case requests <- req:
+			case <-r.done:
+				return
+			}
 		}
 	}()
 
-	return requests, nil
+	return nil
 }
 
 func (r *RabbitMQ) Publish(req types.Request) error {
@@ -99,19 +233,254 @@ func (r *RabbitMQ) Publish(req types.Request) error {
 		return fmt.Errorf("failed to encode message: %v", err)
 	}
 
+	return r.publish(publishing{
+		routingKey: r.queueName,
+		msg: amqp.Publishing{
+			ContentType:   "application/json",
+			ReplyTo:       req.ReplyTo,
+			CorrelationId: req.CorrelationID,
+			Body:          body,
+		},
+	})
+}
+
+// publish sends the message or handles it according to the publish policy
+// if the connection is lost.
+func (r *RabbitMQ) publish(p publishing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return fmt.Errorf("failed to publish message: %w", ErrClosed)
+	}
+
+	if r.connected {
+		err := r.channel.Publish(
+			"",           // exchange
+			p.routingKey, // routing key
+			false,        // mandatory
+			false,        // immediate
+			p.msg,
+		)
+		if err == nil {
+			r.stats.published.Add(1)
+			return nil
+		}
+		if err != amqp.ErrClosed {
+			return fmt.Errorf("failed to publish message: %v", err)
+		}
+		// The connection is lost but the supervisor isn't notified yet
+	}
+
+	if r.opts.publishPolicy == PublishFail {
+		return fmt.Errorf("failed to publish message: %w", ErrNotConnected)
+	}
+	if len(r.buffer) >= r.opts.bufferSize {
+		r.stats.dropped.Add(1)
+		return fmt.Errorf("failed to publish message: %w", ErrBufferFull)
+	}
+	r.buffer = append(r.buffer, p)
+
+	return nil
+}
+
+func (r *RabbitMQ) Ack(req types.Request) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tag, err := r.deliveryTag(req)
+	if err != nil {
+		return err
+	}
+	if err := r.channel.Ack(tag, false); err != nil {
+		return fmt.Errorf("failed to acknowledge message: %v", err)
+	}
+	return nil
+}
+
+func (r *RabbitMQ) Nack(req types.Request, requeue bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tag, err := r.deliveryTag(req)
+	if err != nil {
+		return err
+	}
+	if err := r.channel.Nack(tag, false, requeue); err != nil {
+		return fmt.Errorf("failed to reject message: %v", err)
+	}
+	return nil
+}
+
+// deliveryTag returns the RabbitMQ delivery tag of the request
+// if it was delivered by the current channel.
+func (r *RabbitMQ) deliveryTag(req types.Request) (uint64, error) {
+	generation, tag := decodeDeliveryTag(req.DeliveryTag)
+	if r.closed || !r.connected || generation != r.generation {
+		return 0, ErrStaleDelivery
+	}
+	return tag, nil
+}
+
+// Reply publishes the response to the reply queue of the request.
+// Requests published without a reply queue are silently skipped.
+// Replies are never buffered, the reply queue doesn't survive the reconnect.
+func (r *RabbitMQ) Reply(req types.Request, resp types.Response) error {
+	if req.ReplyTo == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode reply: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.connected {
+		return fmt.Errorf("failed to publish reply: %w", ErrNotConnected)
+	}
 	err = r.channel.Publish(
-		"",           // exchange
-		r.queue.Name, // routing key
-		false,        // mandatory
-		false,        // immediate
+		"",          // exchange
+		req.ReplyTo, // routing key
+		false,       // mandatory
+		false,       // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			ContentType:   "application/json",
+			CorrelationId: req.CorrelationID,
+			Body:          body,
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to publish message: %v", err)
+		return fmt.Errorf("failed to publish reply: %v", err)
 	}
 
 	return nil
 }
+
+// Call publishes the request and waits for the matching reply.
+// ErrTimeout is returned if the reply doesn't arrive within the timeout.
+func (r *RabbitMQ) Call(req types.Request, timeout time.Duration) (types.Response, error) {
+	replyQueue, err := r.replyQueueName()
+	if err != nil {
+		return types.Response{}, err
+	}
+
+	correlationID, err := newCorrelationID()
+	if err != nil {
+		return types.Response{}, err
+	}
+
+	replyChan := make(chan types.Response, 1)
+	r.replyMu.Lock()
+	r.pending[correlationID] = replyChan
+	r.replyMu.Unlock()
+
+	defer func() {
+		r.replyMu.Lock()
+		delete(r.pending, correlationID)
+		r.replyMu.Unlock()
+	}()
+
+	req.ReplyTo = replyQueue
+	req.CorrelationID = correlationID
+	if err := r.Publish(req); err != nil {
+		return types.Response{}, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-replyChan:
+		return resp, nil
+	case <-timer.C:
+		return types.Response{}, ErrTimeout
+	}
+}
+
+// replyQueueName declares the exclusive reply queue on the first use
+// and starts dispatching the replies to the pending calls.
+func (r *RabbitMQ) replyQueueName() (string, error) {
+	r.replyMu.Lock()
+	defer r.replyMu.Unlock()
+
+	if r.replyQueue != "" {
+		return r.replyQueue, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.connected {
+		return "", fmt.Errorf("failed to declare a reply queue: %w", ErrNotConnected)
+	}
+	name, err := r.startReplyConsumer(r.channel)
+	if err != nil {
+		return "", err
+	}
+
+	r.replyQueue = name
+	return r.replyQueue, nil
+}
+
+// startReplyConsumer declares a new exclusive reply queue on the channel
+// and dispatches the replies from it to the pending calls.
+func (r *RabbitMQ) startReplyConsumer(ch *amqp.Channel) (string, error) {
+	q, err := ch.QueueDeclare(
+		"",    // name, generated by the server
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to declare a reply queue: %v", err)
+	}
+
+	msgs, err := ch.Consume(
+		q.Name, // queue
+		"",     // consumer
+		true,   // auto-ack
+		true,   // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to consume replies: %v", err)
+	}
+
+	go func() {
+		for msg := range msgs {
+			var resp types.Response
+			if err := json.Unmarshal(msg.Body, &resp); err != nil {
+				log.Printf("failed to decode reply: %v", err)
+				continue
+			}
+
+			r.replyMu.Lock()
+			replyChan, ok := r.pending[msg.CorrelationId]
+			r.replyMu.Unlock()
+			// Replies to the timed out calls and duplicates are dropped
+			if ok {
+				select {
+				case replyChan <- resp:
+				default:
+				}
+			}
+		}
+	}()
+
+	return q.Name, nil
+}
+
+func newCorrelationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate correlation id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
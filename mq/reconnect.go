@@ -0,0 +1,376 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrNotConnected is returned when the connection to RabbitMQ is lost.
+	ErrNotConnected = errors.New("not connected to RabbitMQ")
+	// ErrBufferFull is returned by Publish when the disconnected buffer is full.
+	ErrBufferFull = errors.New("publish buffer is full")
+	// ErrStaleDelivery is returned by Ack and Nack for a request delivered before
+	// the connection was lost. RabbitMQ redelivers such requests by itself.
+	ErrStaleDelivery = errors.New("request was delivered by a closed channel")
+)
+
+// PublishPolicy defines how the messages published while disconnected are handled.
+type PublishPolicy string
+
+const (
+	// PublishBuffer keeps the messages in memory and publishes them in order
+	// after the reconnect. Publish fails with ErrBufferFull when the buffer is full.
+	PublishBuffer PublishPolicy = "buffer"
+	// PublishFail fails the publish with ErrNotConnected.
+	PublishFail PublishPolicy = "fail"
+)
+
+func ParsePublishPolicy(policy string) (PublishPolicy, error) {
+	switch PublishPolicy(policy) {
+	case PublishBuffer, PublishFail:
+		return PublishPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown publish policy %q. Must be one of: %s, %s", policy, PublishBuffer, PublishFail)
+	}
+}
+
+// Option configures RabbitMQ.
+type Option func(*options)
+
+type options struct {
+	publishPolicy PublishPolicy
+	bufferSize    int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+}
+
+var defaultOptions = options{
+	publishPolicy: PublishBuffer,
+	bufferSize:    1000,
+	minBackoff:    100 * time.Millisecond,
+	maxBackoff:    30 * time.Second,
+}
+
+// WithPublishPolicy sets how the messages published while disconnected are handled.
+// bufferSize limits the number of the buffered messages for PublishBuffer.
+func WithPublishPolicy(policy PublishPolicy, bufferSize int) Option {
+	return func(o *options) {
+		o.publishPolicy = policy
+		o.bufferSize = bufferSize
+	}
+}
+
+// WithReconnectBackoff sets the delay before the first reconnect attempt,
+// it is doubled with every failed attempt up to max.
+func WithReconnectBackoff(min, max time.Duration) Option {
+	return func(o *options) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+type ConnectionState int
+
+const (
+	Connected ConnectionState = iota
+	Disconnected
+	Reconnecting
+	Closed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	case Reconnecting:
+		return "reconnecting"
+	case Closed:
+		return "closed"
+	default:
+		return fmt.Sprintf("ConnectionState(%d)", int(s))
+	}
+}
+
+// ConnectionEvent reports the connection state change.
+type ConnectionEvent struct {
+	State ConnectionState
+	// Err is the reason of the disconnect or the failed reconnect attempt
+	Err error
+	// Attempt and Delay describe the scheduled reconnect attempt
+	Attempt int
+	Delay   time.Duration
+}
+
+// eventsBufferSize is the number of the events kept for the slow reader,
+// the following events are dropped.
+const eventsBufferSize = 64
+
+// Events returns the channel of the connection state changes.
+func (r *RabbitMQ) Events() <-chan ConnectionEvent {
+	return r.events
+}
+
+func (r *RabbitMQ) emit(event ConnectionEvent) {
+	select {
+	case r.events <- event:
+	default:
+	}
+}
+
+// Stats is the snapshot of the connection metrics.
+type Stats struct {
+	State      ConnectionState
+	Reconnects uint64
+	// Published is the number of the messages sent to RabbitMQ
+	Published uint64
+	// Buffered is the number of the messages waiting for the reconnect
+	Buffered int
+	// Dropped is the number of the messages rejected because of the full buffer
+	Dropped uint64
+}
+
+type stats struct {
+	reconnects atomic.Uint64
+	published  atomic.Uint64
+	dropped    atomic.Uint64
+}
+
+func (r *RabbitMQ) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := Disconnected
+	switch {
+	case r.closed:
+		state = Closed
+	case r.connected:
+		state = Connected
+	}
+
+	return Stats{
+		State:      state,
+		Reconnects: r.stats.reconnects.Load(),
+		Published:  r.stats.published.Load(),
+		Buffered:   len(r.buffer),
+		Dropped:    r.stats.dropped.Load(),
+	}
+}
+
+// supervise waits for the connection or channel to be closed and reconnects
+// until the RabbitMQ is closed.
+func (r *RabbitMQ) supervise(conn *amqp.Connection, ch *amqp.Channel) {
+	for {
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		var reason *amqp.Error
+		select {
+		case reason = <-connClosed:
+		case reason = <-chClosed:
+			// The channel can't be used anymore, start from scratch
+			conn.Close()
+		case <-r.done:
+			return
+		}
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			return
+		}
+		r.connected = false
+		r.mu.Unlock()
+
+		event := ConnectionEvent{State: Disconnected}
+		if reason != nil {
+			event.Err = reason
+		}
+		r.emit(event)
+
+		conn, ch = r.reconnect()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// reconnect connects again with backoff. It returns nil if RabbitMQ is closed meanwhile.
+func (r *RabbitMQ) reconnect() (*amqp.Connection, *amqp.Channel) {
+	for attempt := 1; ; attempt++ {
+		delay := backoff(attempt, r.opts.minBackoff, r.opts.maxBackoff)
+		r.emit(ConnectionEvent{State: Reconnecting, Attempt: attempt, Delay: delay})
+
+		select {
+		case <-time.After(delay):
+		case <-r.done:
+			return nil, nil
+		}
+
+		conn, ch, q, err := r.connect()
+		if err != nil {
+			log.Printf("reconnect attempt %d failed: %v", attempt, err)
+			continue
+		}
+		if err := r.resume(conn, ch, q); err != nil {
+			conn.Close()
+			if errors.Is(err, ErrClosed) {
+				return nil, nil
+			}
+			log.Printf("reconnect attempt %d failed: %v", attempt, err)
+			continue
+		}
+
+		return conn, ch
+	}
+}
+
+// resume restores the consumers on the new channel, publishes the buffered
+// messages and makes the new connection the current one. Every attempt uses
+// a new generation, so the requests delivered by a failed attempt are stale.
+func (r *RabbitMQ) resume(conn *amqp.Connection, ch *amqp.Channel, q amqp.Queue) (err error) {
+	r.replyMu.Lock()
+	defer r.replyMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+
+	r.queue = q
+	r.generation++
+	// The consumers of the failed attempt must stop delivering
+	defer func() {
+		if err != nil {
+			ch.Close()
+		}
+	}()
+	if r.requests != nil {
+		if err := r.startConsumer(ch, r.generation, r.requests); err != nil {
+			return err
+		}
+	}
+	if r.replyQueue != "" {
+		// The exclusive reply queue is deleted with the old connection. The buffered
+		// calls are redirected to the new one, they are still pending by their
+		// correlation IDs. The calls published before the disconnect time out.
+		name, err := r.startReplyConsumer(ch)
+		if err != nil {
+			return err
+		}
+		for i := range r.buffer {
+			if r.buffer[i].msg.ReplyTo == r.replyQueue {
+				r.buffer[i].msg.ReplyTo = name
+			}
+		}
+		r.replyQueue = name
+	}
+
+	if err := r.flushBuffer(conn); err != nil {
+		return err
+	}
+
+	r.conn = conn
+	r.channel = ch
+	r.connected = true
+	r.stats.reconnects.Add(1)
+	r.emit(ConnectionEvent{State: Connected})
+
+	return nil
+}
+
+// confirmTimeout limits waiting for RabbitMQ to confirm the buffered messages.
+const confirmTimeout = 5 * time.Second
+
+// flushBuffer publishes the buffered messages in order on a separate channel in the
+// confirm mode. Only the messages confirmed by RabbitMQ are removed from the buffer,
+// the rest are published again by the next attempt.
+func (r *RabbitMQ) flushBuffer(conn *amqp.Connection) error {
+	if len(r.buffer) == 0 {
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %v", err)
+	}
+	defer ch.Close()
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("failed to enable publisher confirms: %v", err)
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, len(r.buffer)))
+
+	sent := 0
+	var publishErr error
+	for _, p := range r.buffer {
+		publishErr = ch.Publish(
+			"",           // exchange
+			p.routingKey, // routing key
+			false,        // mandatory
+			false,        // immediate
+			p.msg,
+		)
+		if publishErr != nil {
+			break
+		}
+		sent++
+	}
+
+	// The confirmations arrive in the publishing order
+	confirmed := 0
+	timeout := time.After(confirmTimeout)
+wait:
+	for confirmed < sent {
+		select {
+		case c, ok := <-confirms:
+			if !ok || !c.Ack {
+				break wait
+			}
+			confirmed++
+		case <-timeout:
+			break wait
+		}
+	}
+	r.buffer = r.buffer[confirmed:]
+	r.stats.published.Add(uint64(confirmed))
+
+	switch {
+	case publishErr != nil:
+		return fmt.Errorf("failed to publish buffered message: %v", publishErr)
+	case len(r.buffer) > 0:
+		return fmt.Errorf("%d buffered messages weren't confirmed", len(r.buffer))
+	}
+	return nil
+}
+
+// backoff returns the delay before the reconnect attempt starting from 1.
+func backoff(attempt int, min, max time.Duration) time.Duration {
+	delay := min
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// The delivery tag of the request keeps the connection generation in the upper
+// bits, so the tags of the closed channels aren't confused with the current ones.
+const deliveryTagBits = 48
+
+func encodeDeliveryTag(generation, tag uint64) uint64 {
+	return generation<<deliveryTagBits | tag
+}
+
+func decodeDeliveryTag(deliveryTag uint64) (uint64, uint64) {
+	return deliveryTag >> deliveryTagBits, deliveryTag & (1<<deliveryTagBits - 1)
+}
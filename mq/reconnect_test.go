@@ -0,0 +1,135 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	min, max := 100*time.Millisecond, time.Second
+
+	assert.Equal(t, 100*time.Millisecond, backoff(1, min, max))
+	assert.Equal(t, 200*time.Millisecond, backoff(2, min, max))
+	assert.Equal(t, 800*time.Millisecond, backoff(4, min, max))
+	assert.Equal(t, time.Second, backoff(5, min, max))
+	assert.Equal(t, time.Second, backoff(100, min, max))
+}
+
+func TestDeliveryTag(t *testing.T) {
+	generation, tag := decodeDeliveryTag(encodeDeliveryTag(3, 12345))
+	assert.Equal(t, uint64(3), generation)
+	assert.Equal(t, uint64(12345), tag)
+}
+
+func TestParsePublishPolicy(t *testing.T) {
+	policy, err := ParsePublishPolicy("buffer")
+	assert.NoError(t, err)
+	assert.Equal(t, PublishBuffer, policy)
+
+	policy, err = ParsePublishPolicy("fail")
+	assert.NoError(t, err)
+	assert.Equal(t, PublishFail, policy)
+
+	_, err = ParsePublishPolicy("retry")
+	assert.Error(t, err)
+}
+
+func waitForState(t *testing.T, r *RabbitMQ, state ConnectionState) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-r.Events():
+			if event.State == state {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %s state", state)
+		}
+	}
+}
+
+func TestRabbitMQ_Reconnect(t *testing.T) {
+	skipWithoutRabbitMQ(t)
+
+	r, err := NewMQ(testRabbitMQURL, "unit_tests_reconnect", WithReconnectBackoff(200*time.Millisecond, time.Second))
+	require.NoError(t, err)
+	defer r.Close()
+	waitForState(t, r, Connected)
+
+	ch, err := r.Consume()
+	require.NoError(t, err)
+
+	// Simulate the connection loss
+	r.mu.Lock()
+	r.conn.Close()
+	r.mu.Unlock()
+	waitForState(t, r, Disconnected)
+
+	// The message published while disconnected is buffered
+	err = r.Publish(types.Request{Key: "k1", Value: "v1", Action: "add"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Stats().Buffered)
+
+	waitForState(t, r, Connected)
+	stats := r.Stats()
+	assert.Equal(t, Connected, stats.State)
+	assert.Equal(t, uint64(1), stats.Reconnects)
+	assert.Equal(t, 0, stats.Buffered)
+
+	// Consuming is resumed to the same channel
+	req := <-ch
+	assert.Equal(t, "k1", req.Key)
+	assert.NoError(t, r.Ack(req))
+}
+
+func TestRabbitMQ_PublishFailWhenDisconnected(t *testing.T) {
+	skipWithoutRabbitMQ(t)
+
+	r, err := NewMQ(testRabbitMQURL, "unit_tests_reconnect", WithPublishPolicy(PublishFail, 0), WithReconnectBackoff(time.Second, time.Second))
+	require.NoError(t, err)
+	defer r.Close()
+
+	r.mu.Lock()
+	r.conn.Close()
+	r.mu.Unlock()
+	waitForState(t, r, Disconnected)
+
+	err = r.Publish(types.Request{Key: "k1", Value: "v1", Action: "add"})
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
+
+func TestRabbitMQ_CallBufferedWhileDisconnected(t *testing.T) {
+	skipWithoutRabbitMQ(t)
+
+	r, err := NewMQ(testRabbitMQURL, "unit_tests_reconnect", WithReconnectBackoff(200*time.Millisecond, time.Second))
+	require.NoError(t, err)
+	defer r.Close()
+	waitForState(t, r, Connected)
+
+	ch, err := r.Consume()
+	require.NoError(t, err)
+	go func() {
+		for req := range ch {
+			r.Reply(req, types.Response{Found: true, Value: req.Value})
+			r.Ack(req)
+		}
+	}()
+
+	// The reply queue is declared by the first call
+	_, err = r.Call(types.Request{Key: "k1", Value: "v1", Action: "get"}, 5*time.Second)
+	require.NoError(t, err)
+
+	r.mu.Lock()
+	r.conn.Close()
+	r.mu.Unlock()
+	waitForState(t, r, Disconnected)
+
+	// The buffered call is answered to the reply queue declared after the reconnect
+	resp, err := r.Call(types.Request{Key: "k2", Value: "v2", Action: "get"}, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", resp.Value)
+}
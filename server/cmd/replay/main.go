@@ -0,0 +1,101 @@
+// Command replay applies the recorded operations to a new ordered map
+// and prints its final content or the difference from the expected one.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+	"server/replay"
+)
+
+func main() {
+	testFile := flag.String("file", "", "Client test data file to replay instead of the server logs")
+	until := flag.Uint64("until", 0, "Stop after the operations logged at this map revision (the test data operation with this sequence number), 0 to replay everything")
+	diffFile := flag.String("diff", "", "File with the expected map content as a JSON object to compare the result to")
+	logFile := flag.String("log-file", os.DevNull, "Log file name for the replayed operations")
+	maxItems := flag.Int("max-items", 0, "Maximum number of the map items the server had, 0 for no limit")
+	eviction := flag.String("eviction", orderermap.FIFO.String(), "Eviction policy the server had: fifo or lru")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [server log files in the rotation order]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if (*testFile == "") == (flag.NArg() == 0) {
+		log.Fatalf("Either the server log files or -file must be set")
+	}
+	evictionPolicy, err := orderermap.ParseEvictionPolicy(*eviction)
+	if err != nil {
+		log.Fatalf("Invalid -eviction value. %v", err)
+	}
+
+	var ops []replay.Operation
+	if *testFile != "" {
+		fileOps, err := readFile(*testFile, replay.ReadTestData)
+		if err != nil {
+			log.Fatalf("Failed to read test data. %v", err)
+		}
+		ops = fileOps
+	}
+	for _, name := range flag.Args() {
+		fileOps, err := readFile(name, replay.ReadLog)
+		if err != nil {
+			log.Fatalf("Failed to read server log. %v", err)
+		}
+		ops = append(ops, fileOps...)
+	}
+
+	logger, err := logger.NewLogger(*logFile)
+	if err != nil {
+		log.Fatalf("Failed to create new logger. %v", err)
+	}
+	dataStorage := replay.Apply(ops, *until, logger, orderermap.WithCapacity(*maxItems, evictionPolicy))
+	logger.Close()
+
+	items := dataStorage.GetAll()
+	if *diffFile == "" {
+		if err := json.NewEncoder(os.Stdout).Encode(items); err != nil {
+			log.Fatalf("Failed to print the map. %v", err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(*diffFile)
+	if err != nil {
+		log.Fatalf("Failed to read expected content. %v", err)
+	}
+	var expected orderermap.Entries[string, string]
+	if err := json.Unmarshal(data, &expected); err != nil {
+		log.Fatalf("Failed to decode expected content. %v", err)
+	}
+
+	diff := replay.Diff(expected, items)
+	for _, line := range diff {
+		fmt.Println(line)
+	}
+	if len(diff) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("The map content matches the expected one")
+}
+
+func readFile(name string, read func(io.Reader) ([]replay.Operation, error)) ([]replay.Operation, error) {
+	file, err := replay.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ops, err := read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return ops, nil
+}
@@ -0,0 +1,184 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	orderermap "server/orderer-map"
+
+	api "github.com/enriquenc/orderer-map-client-server-go/api"
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// Caller sends the request to the request processor and waits for its response.
+type Caller interface {
+	Call(req types.Request, timeout time.Duration) (types.Response, error)
+}
+
+// Server implements the OrderedMap gRPC service. The requests are sent through
+// the same queue as the ones of the other clients, so they are processed in
+// order with them. The changes are watched on the map the requests are applied to.
+type Server struct {
+	api.UnimplementedOrderedMapServer
+
+	caller      Caller
+	timeout     time.Duration
+	dataStorage *orderermap.StringMap
+
+	shutdown  chan struct{}
+	closeOnce sync.Once
+}
+
+func NewServer(caller Caller, timeout time.Duration, dataStorage *orderermap.StringMap) *Server {
+	return &Server{
+		caller:      caller,
+		timeout:     timeout,
+		dataStorage: dataStorage,
+		shutdown:    make(chan struct{}),
+	}
+}
+
+func (s *Server) Add(ctx context.Context, req *api.AddRequest) (*api.AddResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	request := types.Request{ID: req.RequestId, Action: types.AddItem, Key: req.Key, Value: req.Value}
+	if req.Ttl != nil {
+		if err := req.Ttl.CheckValid(); err != nil || req.Ttl.AsDuration() <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "ttl must be positive")
+		}
+		request.TTL = req.Ttl.AsDuration()
+	}
+	if _, err := s.call(ctx, request); err != nil {
+		return nil, err
+	}
+	return &api.AddResponse{}, nil
+}
+
+func (s *Server) Remove(ctx context.Context, req *api.RemoveRequest) (*api.RemoveResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	resp, err := s.call(ctx, types.Request{ID: req.RequestId, Action: types.RemoveItem, Key: req.Key})
+	if err != nil {
+		return nil, err
+	}
+	return &api.RemoveResponse{Found: resp.Found}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *api.GetRequest) (*api.GetResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+	resp, err := s.call(ctx, types.Request{Action: types.GetItem, Key: req.Key})
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetResponse{Found: resp.Found, Value: resp.Value}, nil
+}
+
+func (s *Server) GetAll(ctx context.Context, req *api.GetAllRequest) (*api.GetAllResponse, error) {
+	resp, err := s.call(ctx, types.Request{Action: types.GetAll})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*api.Item, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		items = append(items, &api.Item{Key: item.Key, Value: item.Value})
+	}
+	return &api.GetAllResponse{Items: items}, nil
+}
+
+// Watch streams the changes of the map in the order they are applied until the client cancels it.
+func (s *Server) Watch(req *api.WatchRequest, stream api.OrderedMap_WatchServer) error {
+	var filter func(string) bool
+	if req.KeyPrefix != "" {
+		filter = orderermap.KeyPrefix(req.KeyPrefix)
+	}
+	after := s.dataStorage.Seq()
+	if req.AfterSeq != nil {
+		after = *req.AfterSeq
+	}
+
+	subscription, err := s.dataStorage.Subscribe(after, filter)
+	switch {
+	case errors.Is(err, orderermap.ErrHistoryCompacted), errors.Is(err, orderermap.ErrUnknownSeq):
+		return status.Error(codes.OutOfRange, err.Error())
+	case err != nil:
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer subscription.Close()
+
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return status.Error(codes.ResourceExhausted, subscription.Err().Error())
+			}
+			if err := stream.Send(watchEvent(event)); err != nil {
+				return err
+			}
+		case <-s.shutdown:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func watchEvent(event orderermap.Event[string, string]) *api.WatchEvent {
+	result := &api.WatchEvent{Seq: event.Seq, Key: event.Key, Value: event.Value}
+	switch event.Type {
+	case orderermap.Added:
+		result.Type = api.WatchEvent_TYPE_ADDED
+	case orderermap.Updated:
+		result.Type = api.WatchEvent_TYPE_UPDATED
+	case orderermap.Removed:
+		result.Type = api.WatchEvent_TYPE_REMOVED
+	case orderermap.Expired:
+		result.Type = api.WatchEvent_TYPE_EXPIRED
+	case orderermap.Evicted:
+		result.Type = api.WatchEvent_TYPE_EVICTED
+	case orderermap.Moved:
+		result.Type = api.WatchEvent_TYPE_MOVED
+	}
+	return result
+}
+
+// Close ends the watch streams, so the gRPC server can be stopped gracefully.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+// call sends the request and converts the failure to the gRPC status error.
+func (s *Server) call(ctx context.Context, req types.Request) (types.Response, error) {
+	if req.ID == "" {
+		req.ID = types.NewRequestID()
+	}
+
+	timeout := s.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if untilDeadline := time.Until(deadline); untilDeadline < timeout {
+			timeout = untilDeadline
+		}
+	}
+
+	resp, err := s.caller.Call(req, timeout)
+	switch {
+	case errors.Is(err, mq.ErrTimeout):
+		return resp, status.Error(codes.DeadlineExceeded, "timed out waiting for the response")
+	case err != nil:
+		return resp, status.Error(codes.Unavailable, err.Error())
+	case resp.Error != "":
+		return resp, status.Error(codes.InvalidArgument, resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,176 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+	requestmanager "server/request-manager"
+
+	api "github.com/enriquenc/orderer-map-client-server-go/api"
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+)
+
+func TestServer(t *testing.T) {
+	myLogger, err := logger.NewLogger(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	broker := mq.NewMemoryBroker()
+	reqs, err := broker.Consume()
+	if err != nil {
+		t.Fatalf("Error consuming: %v", err)
+	}
+
+	dataStorage := orderermap.NewOrderedMap()
+	server := NewServer(broker, time.Second, dataStorage)
+	done := make(chan struct{})
+	go func() {
+		requestmanager.ProcessRequests(reqs, myLogger,
+			requestmanager.WithResponder(broker),
+			requestmanager.WithAcknowledger(broker),
+			requestmanager.WithStorage(dataStorage))
+		close(done)
+	}()
+	defer func() {
+		broker.Close()
+		<-done
+	}()
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	api.RegisterOrderedMapServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	defer func() {
+		server.Close()
+		grpcServer.GracefulStop()
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Error dialing: %v", err)
+	}
+	defer conn.Close()
+	client := api.NewOrderedMapClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Watch all the changes from the start
+	watch, err := client.Watch(ctx, &api.WatchRequest{AfterSeq: proto.Uint64(0)})
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+
+	if _, err := client.Add(ctx, &api.AddRequest{Key: "a", Value: "1"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := client.Add(ctx, &api.AddRequest{Key: "b", Value: "2"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	get, err := client.Get(ctx, &api.GetRequest{Key: "a"})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !proto.Equal(get, &api.GetResponse{Found: true, Value: "1"}) {
+		t.Errorf("Get = %v, expected found value 1", get)
+	}
+
+	remove, err := client.Remove(ctx, &api.RemoveRequest{Key: "a"})
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if !remove.Found {
+		t.Errorf("Remove found = false, expected true")
+	}
+	remove, err = client.Remove(ctx, &api.RemoveRequest{Key: "a"})
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if remove.Found {
+		t.Errorf("Remove of missing key found = true, expected false")
+	}
+
+	if _, err := client.Add(ctx, &api.AddRequest{Key: "a", Value: "3"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	all, err := client.GetAll(ctx, &api.GetAllRequest{})
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	expectedItems := &api.GetAllResponse{Items: []*api.Item{{Key: "b", Value: "2"}, {Key: "a", Value: "3"}}}
+	if !proto.Equal(all, expectedItems) {
+		t.Errorf("GetAll = %v, expected %v", all, expectedItems)
+	}
+
+	if _, err := client.Get(ctx, &api.GetRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Get without key error = %v, expected InvalidArgument", err)
+	}
+
+	if _, err := client.Add(ctx, &api.AddRequest{Key: "b", Value: "4"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := client.Add(ctx, &api.AddRequest{Key: "c", Value: "5", Ttl: durationpb.New(50 * time.Millisecond)}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := client.Add(ctx, &api.AddRequest{Key: "d", Value: "6", Ttl: durationpb.New(-time.Second)}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Add with negative TTL error = %v, expected InvalidArgument", err)
+	}
+
+	expectEvents(t, watch, []*api.WatchEvent{
+		{Seq: 1, Type: api.WatchEvent_TYPE_ADDED, Key: "a", Value: "1"},
+		{Seq: 2, Type: api.WatchEvent_TYPE_ADDED, Key: "b", Value: "2"},
+		{Seq: 3, Type: api.WatchEvent_TYPE_REMOVED, Key: "a", Value: "1"},
+		{Seq: 4, Type: api.WatchEvent_TYPE_ADDED, Key: "a", Value: "3"},
+		{Seq: 5, Type: api.WatchEvent_TYPE_UPDATED, Key: "b", Value: "4"},
+		{Seq: 6, Type: api.WatchEvent_TYPE_ADDED, Key: "c", Value: "5"},
+		{Seq: 7, Type: api.WatchEvent_TYPE_EXPIRED, Key: "c", Value: "5"},
+	})
+
+	// Resume after the change 3 watching the key a only
+	resumed, err := client.Watch(ctx, &api.WatchRequest{KeyPrefix: "a", AfterSeq: proto.Uint64(3)})
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+	expectEvents(t, resumed, []*api.WatchEvent{
+		{Seq: 4, Type: api.WatchEvent_TYPE_ADDED, Key: "a", Value: "3"},
+	})
+
+	future, err := client.Watch(ctx, &api.WatchRequest{AfterSeq: proto.Uint64(100)})
+	if err != nil {
+		t.Fatalf("Error watching: %v", err)
+	}
+	if _, err := future.Recv(); status.Code(err) != codes.OutOfRange {
+		t.Errorf("Watch after unknown change error = %v, expected OutOfRange", err)
+	}
+}
+
+func expectEvents(t *testing.T, watch api.OrderedMap_WatchClient, expected []*api.WatchEvent) {
+	t.Helper()
+	for i, want := range expected {
+		event, err := watch.Recv()
+		if err != nil {
+			t.Fatalf("Event %d: receive failed: %v", i, err)
+		}
+		if !proto.Equal(event, want) {
+			t.Errorf("Event %d = %v, expected %v", i, event, want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// itemsPath is the prefix of the REST resources, the item key follows it.
+const itemsPath = "/items"
+
+// maxBodySize limits the size of the request body.
+const maxBodySize = 1 << 20
+
+// Caller sends the request to the request processor and waits for its response.
+type Caller interface {
+	Call(req types.Request, timeout time.Duration) (types.Response, error)
+}
+
+// Item is the JSON representation of a single map item.
+type Item struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler serves the map over REST:
+//
+//	GET    /items        all the items as a JSON object in the insertion order
+//	GET    /items/{key}  the item, 404 if the key doesn't exist
+//	PUT    /items/{key}  adds the item with the value from the {"value": "..."} body,
+//	                     the optional "ttl" duration like "30s" makes it expire
+//	DELETE /items/{key}  removes the item, 404 if the key doesn't exist
+//
+// The requests are sent through the same queue as the ones of the other clients,
+// so they are processed in order with them.
+type Handler struct {
+	caller  Caller
+	timeout time.Duration
+}
+
+func NewHandler(caller Caller, timeout time.Duration) *Handler {
+	return &Handler{caller: caller, timeout: timeout}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.EscapedPath()
+	if path == itemsPath || path == itemsPath+"/" {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.getAll(w)
+		return
+	}
+
+	if !strings.HasPrefix(path, itemsPath+"/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	// The key may contain the escaped slashes
+	key, err := url.PathUnescape(strings.TrimPrefix(path, itemsPath+"/"))
+	if err != nil || key == "" {
+		writeError(w, http.StatusBadRequest, "invalid key")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, key)
+	case http.MethodPut:
+		h.add(w, r, key)
+	case http.MethodDelete:
+		h.remove(w, key)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+func (h *Handler) getAll(w http.ResponseWriter) {
+	resp, ok := h.call(w, types.Request{Action: types.GetAll})
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, resp.Items)
+}
+
+func (h *Handler) get(w http.ResponseWriter, key string) {
+	resp, ok := h.call(w, types.Request{Action: types.GetItem, Key: key})
+	if !ok {
+		return
+	}
+	if !resp.Found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("key %s doesn't exist", key))
+		return
+	}
+	writeJSON(w, http.StatusOK, Item{Key: key, Value: resp.Value})
+}
+
+func (h *Handler) add(w http.ResponseWriter, r *http.Request, key string) {
+	var body struct {
+		Value *string `json:"value"`
+		TTL   string  `json:"ttl"`
+	}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	if err := decoder.Decode(&body); err != nil || body.Value == nil {
+		writeError(w, http.StatusBadRequest, `the body must be a JSON object with the "value" string`)
+		return
+	}
+	req := types.Request{Action: types.AddItem, Key: key, Value: *body.Value}
+	if body.TTL != "" {
+		ttl, err := time.ParseDuration(body.TTL)
+		if err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, `the "ttl" must be a positive duration like "30s"`)
+			return
+		}
+		req.TTL = ttl
+	}
+
+	if _, ok := h.call(w, req); !ok {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) remove(w http.ResponseWriter, key string) {
+	resp, ok := h.call(w, types.Request{Action: types.RemoveItem, Key: key})
+	if !ok {
+		return
+	}
+	if !resp.Found {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("key %s doesn't exist", key))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// call sends the request and writes the error response if it failed.
+func (h *Handler) call(w http.ResponseWriter, req types.Request) (types.Response, bool) {
+	req.ID = types.NewRequestID()
+	resp, err := h.caller.Call(req, h.timeout)
+	switch {
+	case errors.Is(err, mq.ErrTimeout):
+		writeError(w, http.StatusGatewayTimeout, "timed out waiting for the response")
+		return resp, false
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return resp, false
+	case resp.Error != "":
+		writeError(w, http.StatusBadRequest, resp.Error)
+		return resp, false
+	}
+	return resp, true
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write HTTP response: %v", err)
+	}
+}
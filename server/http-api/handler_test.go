@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	logger "server/logger"
+	requestmanager "server/request-manager"
+
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestHandler(t *testing.T) {
+	myLogger, err := logger.NewLogger(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	broker := mq.NewMemoryBroker()
+	reqs, err := broker.Consume()
+	if err != nil {
+		t.Fatalf("Error consuming: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		requestmanager.ProcessRequests(reqs, myLogger, requestmanager.WithResponder(broker), requestmanager.WithAcknowledger(broker))
+		close(done)
+	}()
+	defer func() {
+		broker.Close()
+		<-done
+	}()
+
+	server := httptest.NewServer(NewHandler(broker, time.Second))
+	defer server.Close()
+
+	steps := []struct {
+		method, path, body string
+		status             int
+		expected           string
+	}{
+		{http.MethodPut, "/items/a", `{"value":"1"}`, http.StatusNoContent, ""},
+		{http.MethodPut, "/items/b%2Fc", `{"value":"2"}`, http.StatusNoContent, ""},
+		{http.MethodGet, "/items/a", "", http.StatusOK, `{"key":"a","value":"1"}`},
+		{http.MethodGet, "/items/b%2Fc", "", http.StatusOK, `{"key":"b/c","value":"2"}`},
+		{http.MethodGet, "/items/x", "", http.StatusNotFound, `{"error":"key x doesn't exist"}`},
+		{http.MethodDelete, "/items/a", "", http.StatusNoContent, ""},
+		{http.MethodDelete, "/items/a", "", http.StatusNotFound, `{"error":"key a doesn't exist"}`},
+		{http.MethodPut, "/items/a", `{"value":"3"}`, http.StatusNoContent, ""},
+		{http.MethodGet, "/items", "", http.StatusOK, `{"b/c":"2","a":"3"}`},
+		{http.MethodPut, "/items/d", `{"value":"4","ttl":"1h"}`, http.StatusNoContent, ""},
+		{http.MethodGet, "/items/d", "", http.StatusOK, `{"key":"d","value":"4"}`},
+		{http.MethodPut, "/items/e", `{"value":"5","ttl":"-1s"}`, http.StatusBadRequest, `{"error":"the \"ttl\" must be a positive duration like \"30s\""}`},
+		{http.MethodPut, "/items/a", `"3"`, http.StatusBadRequest, `{"error":"the body must be a JSON object with the \"value\" string"}`},
+		{http.MethodPost, "/items", "", http.StatusMethodNotAllowed, `{"error":"method not allowed"}`},
+		{http.MethodGet, "/keys", "", http.StatusNotFound, `{"error":"not found"}`},
+	}
+
+	for i, step := range steps {
+		req, err := http.NewRequest(step.method, server.URL+step.path, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatalf("Step %d: error creating request: %v", i, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Step %d: request failed: %v", i, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Step %d: error reading response: %v", i, err)
+		}
+
+		if resp.StatusCode != step.status {
+			t.Errorf("Step %d: %s %s status = %d, expected %d", i, step.method, step.path, resp.StatusCode, step.status)
+		}
+		if got := strings.TrimSpace(string(body)); got != step.expected {
+			t.Errorf("Step %d: %s %s body = %s, expected %s", i, step.method, step.path, got, step.expected)
+		}
+	}
+}
+
+// timeoutCaller never gets the response.
+type timeoutCaller struct{}
+
+func (timeoutCaller) Call(req types.Request, timeout time.Duration) (types.Response, error) {
+	return types.Response{}, mq.ErrTimeout
+}
+
+func TestHandler_Timeout(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewHandler(timeoutCaller{}, time.Second).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/items/a", nil))
+
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("Status = %d, expected %d", recorder.Code, http.StatusGatewayTimeout)
+	}
+}
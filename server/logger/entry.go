@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Level is the severity of the log entry.
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel returns the level by its name.
+func ParseLevel(s string) (Level, error) {
+	for l := Debug; l <= Error; l++ {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+	return Debug, fmt.Errorf("unknown log level %q", s)
+}
+
+// Format is the layout of the log lines.
+type Format string
+
+const (
+	// Text writes the sequence number followed by the message.
+	Text Format = "text"
+	// JSON writes every entry as a JSON object on its own line.
+	JSON Format = "json"
+)
+
+// ParseFormat returns the format by its name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Text, JSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q", s)
+}
+
+// Entry is a log message of the operation with its position in the processing order.
+type Entry struct {
+	Seq    uint64
+	Time   time.Time
+	Level  Level
+	Action string
+	Key    string
+	Value  string
+	// Mark is the key the item is inserted next to by the positional actions
+	Mark string
+	// Result is the short outcome of the operation, e.g. "added" or "not found"
+	Result string
+	// Revision is the revision of the map after the operation
+	Revision  uint64
+	RequestID string
+	// Latency is the time the operation took to be processed
+	Latency time.Duration
+	Message string
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("#%d %s", e.Seq, e.Message)
+}
+
+type jsonEntry struct {
+	Time      string  `json:"time"`
+	Level     string  `json:"level"`
+	Seq       uint64  `json:"seq"`
+	Action    string  `json:"action,omitempty"`
+	Key       string  `json:"key,omitempty"`
+	Value     string  `json:"value,omitempty"`
+	Mark      string  `json:"mark,omitempty"`
+	Result    string  `json:"result,omitempty"`
+	Revision  uint64  `json:"revision,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+	Message   string  `json:"msg"`
+}
+
+// MarshalJSON encodes the entry as a flat object with the time in RFC 3339 format.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonEntry{
+		Time:      e.Time.UTC().Format(time.RFC3339Nano),
+		Level:     e.Level.String(),
+		Seq:       e.Seq,
+		Action:    e.Action,
+		Key:       e.Key,
+		Value:     e.Value,
+		Mark:      e.Mark,
+		Result:    e.Result,
+		Revision:  e.Revision,
+		RequestID: e.RequestID,
+		LatencyMS: float64(e.Latency) / float64(time.Millisecond),
+		Message:   e.Message,
+	})
+}
+
+// UnmarshalJSON decodes the entry written in the JSON format.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var je jsonEntry
+	if err := json.Unmarshal(data, &je); err != nil {
+		return err
+	}
+
+	var entry Entry
+	if je.Time != "" {
+		t, err := time.Parse(time.RFC3339Nano, je.Time)
+		if err != nil {
+			return fmt.Errorf("invalid log entry time: %v", err)
+		}
+		entry.Time = t
+	}
+	if je.Level != "" {
+		level, err := ParseLevel(je.Level)
+		if err != nil {
+			return err
+		}
+		entry.Level = level
+	}
+	entry.Seq = je.Seq
+	entry.Action = je.Action
+	entry.Key = je.Key
+	entry.Value = je.Value
+	entry.Mark = je.Mark
+	entry.Result = je.Result
+	entry.Revision = je.Revision
+	entry.RequestID = je.RequestID
+	entry.Latency = time.Duration(je.LatencyMS * float64(time.Millisecond))
+	entry.Message = je.Message
+
+	*e = entry
+	return nil
+}
+
+// format returns the log line of the entry without the trailing newline.
+func (e Entry) format(f Format) string {
+	if f == JSON {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return e.String()
+		}
+		return string(b)
+	}
+	return e.String()
+}
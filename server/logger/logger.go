@@ -1,26 +1,73 @@
 package logger
 
 import (
+	"bufio"
 	"fmt"
+	"log"
 	"os"
+	"sync"
+	"time"
 )
 
-type Logger struct {
-	file      *os.File
-	writeChan chan string
-	done      chan struct{}
+// Option configures the Logger.
+type Option func(*Logger)
+
+// WithFormat sets the layout of the entries logged with LogEntry.
+func WithFormat(format Format) Option {
+	return func(l *Logger) {
+		l.format = format
+	}
 }
 
-func NewLogger(filename string) (*Logger, error) {
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		return nil, err
+// WithLevel drops the entries less severe than the level.
+func WithLevel(level Level) Option {
+	return func(l *Logger) {
+		l.level = level
 	}
+}
 
+// Logger writes the messages to the file in background in the order they were logged.
+// Logging never blocks: the messages are queued until the writer goroutine takes them.
+type Logger struct {
+	filename string
+	format   Format
+	level    Level
+	rotation Rotation
+
+	// The file state is guarded by fileMu as Reopen is called outside of the writer goroutine
+	fileMu   sync.Mutex
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	openedAt time.Time
+	// rotationRetryAt delays the rotation after a failed one
+	rotationRetryAt time.Time
+	// Compression and removal of the rotated files
+	maintenance sync.WaitGroup
+	cleanupMu   sync.Mutex
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []string
+	pending int
+	closed  bool
+	done    chan struct{}
+}
+
+func NewLogger(filename string, opts ...Option) (*Logger, error) {
 	l := &Logger{
-		file:      file,
-		writeChan: make(chan string),
-		done:      make(chan struct{}),
+		filename: filename,
+		format:   Text,
+		level:    Debug,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.cond = sync.NewCond(&l.mu)
+
+	if err := l.open(); err != nil {
+		return nil, err
 	}
 
 	go l.writeLoop()
@@ -29,18 +76,131 @@ func NewLogger(filename string) (*Logger, error) {
 }
 
 func (l *Logger) Log(message string) {
-	l.writeChan <- message
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Messages logged after Close are dropped
+	if l.closed {
+		return
+	}
+	l.queue = append(l.queue, message)
+	l.pending++
+	l.cond.Broadcast()
+}
+
+// Enabled reports whether the entries of the level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// LogEntry logs the entry in the logger format unless its level is filtered out.
+func (l *Logger) LogEntry(entry Entry) {
+	if !l.Enabled(entry.Level) {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	l.Log(entry.format(l.format))
 }
 
+// Flush waits until all the logged messages are written to the file.
+func (l *Logger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.pending > 0 {
+		l.cond.Wait()
+	}
+}
+
+// Close writes the queued messages and closes the file.
 func (l *Logger) Close() {
-	close(l.writeChan)
+	l.mu.Lock()
+	l.closed = true
+	l.cond.Broadcast()
+	l.mu.Unlock()
+
 	<-l.done
 }
 
 func (l *Logger) writeLoop() {
-	for message := range l.writeChan {
-		fmt.Fprintln(l.file, message)
+	l.mu.Lock()
+	for {
+		for len(l.queue) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if len(l.queue) == 0 && l.closed {
+			break
+		}
+
+		// Write the queued messages in a batch without holding the lock
+		batch := l.queue
+		l.queue = nil
+		l.mu.Unlock()
+
+		l.write(batch)
+
+		l.mu.Lock()
+		l.pending -= len(batch)
+		l.cond.Broadcast()
 	}
+	l.mu.Unlock()
+
+	l.fileMu.Lock()
 	l.file.Close()
+	l.fileMu.Unlock()
+	l.maintenance.Wait()
 	close(l.done)
 }
+
+// open opens the log file for appending.
+func (l *Logger) open() error {
+	file, err := os.OpenFile(l.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.writer = bufio.NewWriter(file)
+	l.size = info.Size()
+	l.openedAt = time.Now()
+	return nil
+}
+
+// write writes the messages to the file rotating it when needed.
+func (l *Logger) write(messages []string) {
+	l.fileMu.Lock()
+	defer l.fileMu.Unlock()
+
+	for _, message := range messages {
+		if l.shouldRotate(int64(len(message) + 1)) {
+			l.writer.Flush()
+			if err := l.rotate(); err != nil {
+				log.Printf("Failed to rotate log file %s: %v", l.filename, err)
+			}
+		}
+		n, _ := fmt.Fprintln(l.writer, message)
+		l.size += int64(n)
+	}
+	l.writer.Flush()
+}
+
+// Reopen closes and opens the log file again, e.g. after it was moved by
+// an external tool like logrotate.
+func (l *Logger) Reopen() error {
+	l.fileMu.Lock()
+	defer l.fileMu.Unlock()
+
+	l.writer.Flush()
+	file := l.file
+	if err := l.open(); err != nil {
+		return err
+	}
+	return file.Close()
+}
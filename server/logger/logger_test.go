@@ -1,9 +1,13 @@
 package logger
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLogger(t *testing.T) {
@@ -23,6 +27,7 @@ func TestLogger(t *testing.T) {
 
 	// Write a log message to the logger
 	log.Log("test message")
+	log.Flush()
 
 	// Read the contents of the log file and verify that it contains the log message
 	contents, err := ioutil.ReadFile(tempFile.Name())
@@ -42,3 +47,97 @@ func TestLoggerError(t *testing.T) {
 		t.Errorf("Expected error when creating logger with invalid filename, but got nil")
 	}
 }
+
+func TestLoggerOrder(t *testing.T) {
+	tempFile, err := ioutil.TempFile("", "test-log")
+	if err != nil {
+		t.Fatalf("Failed to create temporary log file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	log, err := NewLogger(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	var expected strings.Builder
+	for i := uint64(1); i <= 1000; i++ {
+		log.LogEntry(Entry{Seq: i, Message: fmt.Sprintf("message %d", i)})
+		fmt.Fprintf(&expected, "#%d message %d\n", i, i)
+	}
+	log.Close()
+
+	// Messages logged after Close are dropped
+	log.Log("late message")
+
+	contents, err := ioutil.ReadFile(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if string(contents) != expected.String() {
+		t.Errorf("Log file contents do not match the logging order")
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	tempFile, err := ioutil.TempFile("", "test-log")
+	if err != nil {
+		t.Fatalf("Failed to create temporary log file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	log, err := NewLogger(tempFile.Name(), WithFormat(JSON), WithLevel(Info))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	entry := Entry{
+		Seq:       1,
+		Time:      time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+		Level:     Info,
+		Action:    "add",
+		Key:       "k",
+		Value:     "v",
+		Result:    "added",
+		RequestID: "id",
+		Latency:   1500 * time.Microsecond,
+		Message:   "[add] Added key k with value v",
+	}
+	log.LogEntry(entry)
+	// Filtered out by the level
+	log.LogEntry(Entry{Seq: 2, Level: Debug, Action: "get", Key: "k"})
+	log.Close()
+
+	contents, err := ioutil.ReadFile(tempFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	expected := `{"time":"2023-05-01T12:00:00Z","level":"info","seq":1,"action":"add","key":"k","value":"v",` +
+		`"result":"added","request_id":"id","latency_ms":1.5,"msg":"[add] Added key k with value v"}` + "\n"
+	if string(contents) != expected {
+		t.Errorf("Log file contents (%q) do not match expected contents (%q)", contents, expected)
+	}
+
+	// The entry is decoded back for the replay
+	var decoded Entry
+	if err := json.Unmarshal(contents, &decoded); err != nil {
+		t.Fatalf("Failed to decode log entry: %v", err)
+	}
+	if decoded != entry {
+		t.Errorf("Decoded entry %+v, expected %+v", decoded, entry)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []Level{Debug, Info, Warn, Error} {
+		if parsed, err := ParseLevel(level.String()); err != nil || parsed != level {
+			t.Errorf("ParseLevel(%q) = (%v, %v), expected (%v, nil)", level.String(), parsed, err, level)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("Expected error when parsing unknown level")
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("Expected error when parsing unknown format")
+	}
+}
@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotatedTimeFormat is the suffix of the rotated files. It sorts in the rotation order.
+const rotatedTimeFormat = "20060102T150405.000"
+
+// Rotation configures when the log file is rotated and how many rotated files are kept.
+// The zero value disables the rotation.
+type Rotation struct {
+	// MaxSize is the size in bytes the file is rotated after, 0 for no limit
+	MaxSize int64
+	// Interval is the age of the file it's rotated after, 0 for no limit.
+	// The file is rotated by the first write after the interval has passed.
+	Interval time.Duration
+	// MaxFiles is the number of the rotated files kept, 0 to keep all of them
+	MaxFiles int
+	// Compress makes the rotated files be compressed with gzip
+	Compress bool
+}
+
+// WithRotation makes the logger rotate the file according to the policy.
+// The rotated files are named after the file with the rotation time appended.
+func WithRotation(rotation Rotation) Option {
+	return func(l *Logger) {
+		l.rotation = rotation
+	}
+}
+
+// rotationRetryDelay is the time the failed rotation is retried after,
+// the writes go to the current file meanwhile.
+const rotationRetryDelay = time.Minute
+
+// shouldRotate reports whether the file should be rotated before writing n bytes.
+func (l *Logger) shouldRotate(n int64) bool {
+	if time.Now().Before(l.rotationRetryAt) {
+		return false
+	}
+	if l.rotation.MaxSize > 0 && l.size > 0 && l.size+n > l.rotation.MaxSize {
+		return true
+	}
+	if l.rotation.Interval > 0 && time.Since(l.openedAt) >= l.rotation.Interval {
+		return l.size > 0
+	}
+	return false
+}
+
+// rotate renames the current file and opens a new one. The current file is kept
+// open until the new one is opened, so the logger keeps writing to it if the rotation
+// fails. The rotated file is compressed and the old ones are removed in background.
+func (l *Logger) rotate() (err error) {
+	defer func() {
+		if err != nil {
+			l.rotationRetryAt = time.Now().Add(rotationRetryDelay)
+		}
+	}()
+
+	rotated, err := l.rotatedName(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(l.filename, rotated); err != nil {
+		return err
+	}
+	current := l.file
+	if err := l.open(); err != nil {
+		// Keep writing to the same file under its name
+		if renameErr := os.Rename(rotated, l.filename); renameErr != nil {
+			log.Printf("Failed to restore log file %s: %v", l.filename, renameErr)
+		}
+		return err
+	}
+	if err := current.Close(); err != nil {
+		log.Printf("Failed to close rotated log file %s: %v", rotated, err)
+	}
+
+	l.maintenance.Add(1)
+	go func() {
+		defer l.maintenance.Done()
+		l.cleanup(rotated)
+	}()
+	return nil
+}
+
+// rotatedName returns the unused name for the file rotated at the time.
+// The time is moved forward if the name is taken, so the names keep the rotation order.
+func (l *Logger) rotatedName(at time.Time) (string, error) {
+	for {
+		name := l.filename + "." + at.Format(rotatedTimeFormat)
+		for _, candidate := range []string{name, name + ".gz"} {
+			if _, err := os.Stat(candidate); err == nil {
+				name = ""
+				break
+			} else if !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+		if name != "" {
+			return name, nil
+		}
+		at = at.Add(time.Millisecond)
+	}
+}
+
+// cleanup compresses the rotated file and removes the ones exceeding the retention.
+// The cleanups run one at a time.
+func (l *Logger) cleanup(rotated string) {
+	l.cleanupMu.Lock()
+	defer l.cleanupMu.Unlock()
+
+	if l.rotation.Compress {
+		if err := compress(rotated); err != nil {
+			log.Printf("Failed to compress log file %s: %v", rotated, err)
+		}
+	}
+	if l.rotation.MaxFiles > 0 {
+		if err := l.prune(); err != nil {
+			log.Printf("Failed to remove old log files: %v", err)
+		}
+	}
+}
+
+// compress replaces the file with its gzip compressed copy.
+func compress(filename string) error {
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(filename+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	return os.Remove(filename)
+}
+
+// prune removes the oldest rotated files above MaxFiles.
+func (l *Logger) prune() error {
+	rotated, err := l.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for len(rotated) > l.rotation.MaxFiles {
+		if err := os.Remove(rotated[0]); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// rotatedFiles returns the rotated files from the oldest to the newest.
+func (l *Logger) rotatedFiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(l.filename))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(l.filename) + "."
+	var rotated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		suffix := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		if _, err := time.Parse(rotatedTimeFormat, suffix); err != nil {
+			continue
+		}
+		rotated = append(rotated, filepath.Join(filepath.Dir(l.filename), name))
+	}
+	sort.Strings(rotated)
+	return rotated, nil
+}
@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "server.log")
+
+	// Every file fits two 10 bytes messages
+	log, err := NewLogger(filename, WithRotation(Rotation{MaxSize: 20, MaxFiles: 2, Compress: true}))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	for _, message := range []string{"message 1", "message 2", "message 3", "message 4", "message 5", "message 6", "message 7"} {
+		log.Log(message)
+		// Write the messages one by one to rotate the files at the same positions
+		log.Flush()
+	}
+	log.Close()
+
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if string(contents) != "message 7\n" {
+		t.Errorf("Log file contents (%q) do not match expected contents (%q)", contents, "message 7\n")
+	}
+
+	rotated, err := log.rotatedFiles()
+	if err != nil {
+		t.Fatalf("Failed to list rotated files: %v", err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("Rotated files = %v, expected 2 files", rotated)
+	}
+	expected := []string{"message 3\nmessage 4\n", "message 5\nmessage 6\n"}
+	for i, name := range rotated {
+		if !strings.HasSuffix(name, ".gz") {
+			t.Errorf("Rotated file %s is not compressed", name)
+			continue
+		}
+		if contents := readGzip(t, name); contents != expected[i] {
+			t.Errorf("Rotated file %s contents (%q) do not match expected contents (%q)", name, contents, expected[i])
+		}
+	}
+}
+
+func TestLoggerRotateByTime(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "server.log")
+
+	log, err := NewLogger(filename, WithRotation(Rotation{Interval: 50 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	log.Log("message 1")
+	log.Flush()
+	time.Sleep(60 * time.Millisecond)
+	log.Log("message 2")
+	log.Close()
+
+	rotated, err := log.rotatedFiles()
+	if err != nil {
+		t.Fatalf("Failed to list rotated files: %v", err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("Rotated files = %v, expected 1 file", rotated)
+	}
+	contents, err := ioutil.ReadFile(rotated[0])
+	if err != nil {
+		t.Fatalf("Failed to read rotated file: %v", err)
+	}
+	if string(contents) != "message 1\n" {
+		t.Errorf("Rotated file contents (%q) do not match expected contents (%q)", contents, "message 1\n")
+	}
+}
+
+func TestLoggerReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "server.log")
+
+	log, err := NewLogger(filename)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	log.Log("message 1")
+	log.Flush()
+
+	// Move the file away as logrotate does
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		t.Fatalf("Failed to move log file: %v", err)
+	}
+	if err := log.Reopen(); err != nil {
+		t.Fatalf("Failed to reopen log file: %v", err)
+	}
+	log.Log("message 2")
+	log.Close()
+
+	for name, expected := range map[string]string{filename + ".1": "message 1\n", filename: "message 2\n"} {
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read log file: %v", err)
+		}
+		if string(contents) != expected {
+			t.Errorf("Log file %s contents (%q) do not match expected contents (%q)", name, contents, expected)
+		}
+	}
+}
+
+func TestLoggerRotateFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "server.log")
+
+	log, err := NewLogger(filename, WithRotation(Rotation{MaxSize: 20}))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	log.Log("message 1")
+	log.Log("message 2")
+	log.Flush()
+
+	// The file can't be renamed by the rotation if it's moved away
+	if err := os.Rename(filename, filename+".moved"); err != nil {
+		t.Fatalf("Failed to move log file: %v", err)
+	}
+	log.Log("message 3")
+	log.Flush()
+	if log.rotationRetryAt.IsZero() {
+		t.Errorf("Expected the failed rotation to be delayed")
+	}
+	// The logger keeps writing to the same file until the rotation is retried
+	log.Log("message 4")
+	log.Close()
+
+	contents, err := ioutil.ReadFile(filename + ".moved")
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if expected := "message 1\nmessage 2\nmessage 3\nmessage 4\n"; string(contents) != expected {
+		t.Errorf("Log file contents (%q) do not match expected contents (%q)", contents, expected)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected no new log file, got %v", err)
+	}
+}
+
+func readGzip(t *testing.T, name string) string {
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", name, err)
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	contents, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(contents)
+}
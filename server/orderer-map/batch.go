@@ -0,0 +1,112 @@
+package orderedmap
+
+import "time"
+
+// The changes made between Begin and Commit can be undone with Rollback. The state of
+// every changed item is kept before the change, whatever the retention is, and the
+// changes are undone newest first. The events of the changes are held back until
+// Commit, so the subscribers never see the undone ones.
+
+// batch is the open batch of the changes.
+type batch[K comparable, V any] struct {
+	// revision is the revision of the map when the batch was opened
+	revision uint64
+	undo     []undo[K, V]
+	events   []Event[K, V]
+}
+
+// undo is the state of the item before a change of the batch.
+type undo[K comparable, V any] struct {
+	key K
+	// existed is false if the item was added by the change
+	existed   bool
+	value     V
+	version   uint64
+	expiresAt time.Time
+	// prev is the key of the item preceding the changed one, atHead is set if there was none
+	prev   K
+	atHead bool
+}
+
+// Begin opens a batch of changes, see Rollback. The reads see the changes of the open batch,
+// but Seq and Subscribe don't until it's committed. Only one batch can be open at a time.
+func (m *OrderedMap[K, V]) Begin() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.batch = &batch[K, V]{revision: m.revision}
+}
+
+// Commit closes the batch keeping its changes and passes their events to the subscribers.
+func (m *OrderedMap[K, V]) Commit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.batch
+	m.batch = nil
+	for _, event := range b.events {
+		m.deliver(event)
+	}
+}
+
+// Rollback closes the batch undoing its changes, including the evictions, and
+// returns the map to the revision the batch was opened at.
+func (m *OrderedMap[K, V]) Rollback() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.batch
+	m.batch = nil
+	// The item preceding the changed one isn't changed by the change,
+	// so it's in place when the change is undone
+	for i := len(b.undo) - 1; i >= 0; i-- {
+		u := b.undo[i]
+		if node, exists := m.items[u.key]; exists {
+			m.detach(node)
+			delete(m.items, u.key)
+			m.setExpiry(node, time.Time{})
+		}
+		if !u.existed {
+			continue
+		}
+		restored := &node[K, V]{key: u.key, value: u.value, version: u.version, heapIndex: -1}
+		if u.atHead {
+			m.link(restored, m.head)
+		} else {
+			m.link(restored, m.items[u.prev].next)
+		}
+		m.items[u.key] = restored
+		m.setExpiry(restored, u.expiresAt)
+	}
+
+	m.revision = b.revision
+	kept := len(m.changes)
+	for kept > 0 && m.changes[kept-1].revision > b.revision {
+		kept--
+	}
+	m.changes = m.changes[:kept]
+}
+
+// keepUndo keeps the state of the item before the change of the open batch,
+// the node is nil if the item is added.
+func (m *OrderedMap[K, V]) keepUndo(key K, node *node[K, V]) {
+	u := undo[K, V]{key: key, atHead: true}
+	if node != nil {
+		u.existed = true
+		u.value = node.value
+		u.version = node.version
+		u.expiresAt = node.expiresAt
+		if node.prev != nil {
+			u.prev, u.atHead = node.prev.key, false
+		}
+	}
+	m.batch.undo = append(m.batch.undo, u)
+}
+
+// committedRevision returns the revision of the map without the changes of the open batch.
+func (m *OrderedMap[K, V]) committedRevision() uint64 {
+	if m.batch != nil {
+		return m.batch.revision
+	}
+	return m.revision
+}
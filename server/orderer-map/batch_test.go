@@ -0,0 +1,83 @@
+package orderedmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedMap_BatchRollback(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewOrderedMap(WithCapacity(3, FIFO), WithClock(func() time.Time { return now }))
+	m.Add("a", "1", ExpireAt(now.Add(time.Minute)))
+	m.Add("b", "2")
+	m.Add("c", "3")
+	m.Add("b", "4")
+	items := m.GetAll()
+	versions := m.Versions()
+	expiries := m.Expiries()
+	revision := m.Revision()
+	s, err := m.Subscribe(m.Seq(), nil)
+	require.NoError(t, err)
+	defer s.Close()
+
+	m.Begin()
+	// The new key evicts a
+	m.Add("d", "5")
+	_, err = m.InsertBefore("c", "a", "6")
+	require.NoError(t, err)
+	m.Add("c", "7", ExpireAt(now.Add(time.Hour)))
+	m.Remove("b")
+	m.MoveToFront("c")
+	assert.Equal(t, pairs("c", "7", "a", "6", "d", "5"), m.GetAll())
+	assert.Equal(t, revision, m.Seq(), "the changes of the open batch aren't counted")
+	assert.Empty(t, receive(s))
+	m.Rollback()
+
+	assert.Equal(t, items, m.GetAll())
+	assert.Equal(t, versions, m.Versions())
+	assert.Equal(t, expiries, m.Expiries())
+	assert.Equal(t, revision, m.Revision())
+	assert.Equal(t, revision, m.Seq())
+	for i := 0; i < 3; i++ {
+		entry, ok := m.At(i)
+		require.True(t, ok)
+		assert.Equal(t, items[i], entry)
+	}
+	assert.Empty(t, receive(s))
+	at, err := m.GetAllAtRevision(revision - 1)
+	require.NoError(t, err)
+	assert.Equal(t, pairs("a", "1", "b", "2", "c", "3"), at)
+	_, err = m.GetAllAtRevision(revision + 1)
+	assert.ErrorIs(t, err, ErrFutureRevision)
+
+	// The revisions of the undone changes are taken by the next ones
+	m.Add("e", "8")
+	assert.Equal(t, []Event[string, string]{
+		{Seq: revision + 1, Type: Evicted, Key: "a", Value: "1"},
+		{Seq: revision + 2, Type: Added, Key: "e", Value: "8"},
+	}, receive(s))
+}
+
+func TestOrderedMap_BatchCommit(t *testing.T) {
+	m := NewOrderedMap(WithRetention(0))
+	m.Add("a", "1")
+	s, err := m.Subscribe(m.Seq(), nil)
+	require.NoError(t, err)
+	defer s.Close()
+
+	m.Begin()
+	m.Add("b", "2")
+	m.Remove("a")
+	assert.Empty(t, receive(s))
+	m.Commit()
+
+	assert.Equal(t, pairs("b", "2"), m.GetAll())
+	assert.Equal(t, uint64(3), m.Seq())
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 2, Type: Added, Key: "b", Value: "2"},
+		{Seq: 3, Type: Removed, Key: "a", Value: "1"},
+	}, receive(s))
+}
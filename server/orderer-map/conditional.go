@@ -0,0 +1,137 @@
+package orderedmap
+
+// Every item has a version which is the revision of the map it's added at and is
+// incremented on every update of it. The updates are changes of the map as well, so
+// the version never exceeds the revision, and the key removed and added again never
+// gets a version it had before. The conditional writes change the item only if it's in the
+// expected state, so the concurrent writers don't overwrite the changes of each other.
+// The expired items which aren't removed yet are considered absent.
+
+// AtVersion sets the version of the new item, e.g. to restore it from a snapshot.
+// It's ignored when the existing item is updated.
+func AtVersion(version uint64) AddOption {
+	return func(o *addOptions) {
+		o.version = version
+	}
+}
+
+// Version returns the version of the item, false if the key doesn't exist.
+func (m *OrderedMap[K, V]) Version(key K) (uint64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node := m.live(key)
+	if node == nil {
+		return 0, false
+	}
+	return node.version, true
+}
+
+// Versions returns the versions of the items.
+func (m *OrderedMap[K, V]) Versions() map[K]uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	result := make(map[K]uint64, len(m.items))
+	for key, node := range m.items {
+		if !node.expired(now) {
+			result[key] = node.version
+		}
+	}
+	return result
+}
+
+// AddIfAbsent appends the item to the tail only if the key doesn't exist. It returns false
+// if it does and the items evicted to make room for the new key otherwise.
+func (m *OrderedMap[K, V]) AddIfAbsent(key K, value V, opts ...AddOption) (bool, Entries[K, V]) {
+	o := newAddOptions(opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.live(key) != nil {
+		return false, nil
+	}
+	if node, exists := m.items[key]; exists {
+		// The expired item is replaced by the new one
+		m.unlink(node)
+		m.publish(Expired, key, node.value)
+	}
+	evicted := m.evict(nil)
+	m.insert(key, value, o, nil)
+	return true, evicted
+}
+
+// Update sets the value of the item only if the key exists. It returns false if it doesn't.
+func (m *OrderedMap[K, V]) Update(key K, value V, opts ...AddOption) bool {
+	o := newAddOptions(opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.live(key)
+	if node == nil {
+		return false
+	}
+	m.update(node, value, o)
+	return true
+}
+
+// CompareAndSwap sets the value of the item only if its current value is the old one.
+// It returns false if the key doesn't exist or has another value. The values are
+// compared with ==, so it panics if the value type isn't comparable.
+func (m *OrderedMap[K, V]) CompareAndSwap(key K, old, value V, opts ...AddOption) bool {
+	o := newAddOptions(opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.live(key)
+	if node == nil || any(node.value) != any(old) {
+		return false
+	}
+	m.update(node, value, o)
+	return true
+}
+
+// CompareVersionAndSwap sets the value of the item only if its current version is the
+// given one. It returns false if the key doesn't exist or was changed since that version.
+func (m *OrderedMap[K, V]) CompareVersionAndSwap(key K, version uint64, value V, opts ...AddOption) bool {
+	o := newAddOptions(opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.live(key)
+	if node == nil || node.version != version {
+		return false
+	}
+	m.update(node, value, o)
+	return true
+}
+
+// CompareAndRemove removes the item only if its current value is the old one. It returns
+// false if the key doesn't exist or has another value. The values are compared with ==,
+// so it panics if the value type isn't comparable.
+func (m *OrderedMap[K, V]) CompareAndRemove(key K, old V) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node := m.live(key)
+	if node == nil || any(node.value) != any(old) {
+		return false
+	}
+	m.unlink(node)
+	m.publish(Removed, key, node.value)
+	return true
+}
+
+// live returns the node of the key, nil if it doesn't exist or is expired.
+func (m *OrderedMap[K, V]) live(key K) *node[K, V] {
+	node, exists := m.items[key]
+	if !exists || node.expired(m.now()) {
+		return nil
+	}
+	return node
+}
@@ -0,0 +1,89 @@
+package orderedmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap_Version(t *testing.T) {
+	m := NewOrderedMap()
+	m.Add("a", "1")
+	m.Add("b", "2")
+	m.Add("a", "3")
+	_, err := m.InsertAfter("b", "a", "4")
+	assert.NoError(t, err)
+
+	version, ok := m.Version("a")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), version)
+	assert.Equal(t, map[string]uint64{"a": 3, "b": 2}, m.Versions())
+
+	// The key added again starts at the revision, so it doesn't repeat the versions it had
+	m.Remove("a")
+	_, ok = m.Version("a")
+	assert.False(t, ok)
+	m.Add("a", "5")
+	version, _ = m.Version("a")
+	assert.Equal(t, uint64(7), version)
+	assert.False(t, m.CompareVersionAndSwap("a", 3, "6"))
+
+	m.Add("c", "6", AtVersion(7))
+	version, _ = m.Version("c")
+	assert.Equal(t, uint64(7), version)
+}
+
+func TestOrderedMap_ConditionalWrites(t *testing.T) {
+	m := NewOrderedMap()
+
+	added, _ := m.AddIfAbsent("a", "1")
+	assert.True(t, added)
+	added, _ = m.AddIfAbsent("a", "2")
+	assert.False(t, added)
+
+	assert.True(t, m.Update("a", "3"))
+	assert.False(t, m.Update("b", "3"))
+
+	assert.False(t, m.CompareAndSwap("a", "1", "4"))
+	assert.True(t, m.CompareAndSwap("a", "3", "4"))
+	assert.False(t, m.CompareAndSwap("b", "", "4"))
+
+	assert.False(t, m.CompareVersionAndSwap("a", 2, "5"))
+	assert.True(t, m.CompareVersionAndSwap("a", 3, "5"))
+	version, _ := m.Version("a")
+	assert.Equal(t, uint64(4), version)
+
+	assert.False(t, m.CompareAndRemove("a", "4"))
+	assert.True(t, m.CompareAndRemove("a", "5"))
+	assert.Empty(t, m.GetAll())
+}
+
+func TestOrderedMap_ConditionalWritesExpired(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewOrderedMap(WithClock(func() time.Time { return now }))
+	m.Add("a", "1", ExpireAt(now.Add(time.Second)))
+	m.Add("b", "2")
+	m.Add("a", "3", ExpireAt(now.Add(time.Second)))
+	now = now.Add(time.Second)
+
+	// The expired item is absent for the conditions
+	assert.False(t, m.Update("a", "4"))
+	assert.False(t, m.CompareAndSwap("a", "3", "4"))
+	assert.False(t, m.CompareAndRemove("a", "3"))
+
+	added, _ := m.AddIfAbsent("a", "5")
+	assert.True(t, added)
+	version, _ := m.Version("a")
+	assert.Equal(t, uint64(5), version)
+	assert.Equal(t, pairs("b", "2", "a", "5"), m.GetAll())
+}
+
+func TestOrderedMap_AddIfAbsentEvicts(t *testing.T) {
+	m := NewOrderedMap(WithCapacity(1, FIFO))
+	m.Add("a", "1")
+
+	added, evicted := m.AddIfAbsent("b", "2")
+	assert.True(t, added)
+	assert.Equal(t, pairs("a", "1"), evicted)
+}
@@ -0,0 +1,24 @@
+package orderedmap
+
+import (
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// Entry is a key/value pair of the map.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Entries is an ordered list of the map items. It is encoded to JSON as an
+// object which keeps the order of the keys, e.g. {"key2":"value2","key1":"value1"}.
+// The encoding is shared with the items of the responses.
+type Entries[K comparable, V any] []Entry[K, V]
+
+func (e Entries[K, V]) MarshalJSON() ([]byte, error) {
+	return types.MarshalEntries(e)
+}
+
+func (e *Entries[K, V]) UnmarshalJSON(data []byte) error {
+	return types.UnmarshalEntries((*[]Entry[K, V])(e), data)
+}
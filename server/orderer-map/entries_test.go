@@ -0,0 +1,53 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntries_MarshalJSON(t *testing.T) {
+	m := NewOrderedMap()
+	m.Add("b", "2")
+	m.Add("a=b", "1=2")
+	m.Add("c", "3")
+
+	b, err := json.Marshal(m.GetAll())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"b":"2","a=b":"1=2","c":"3"}`, string(b))
+
+	// Empty map is encoded as an empty object
+	b, err = json.Marshal(NewOrderedMap().GetAll())
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(b))
+}
+
+func TestEntries_MarshalJSON_NumberKeys(t *testing.T) {
+	m := New[int, bool]()
+	m.Add(2, true)
+	m.Add(1, false)
+
+	b, err := json.Marshal(m.GetAll())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"2":true,"1":false}`, string(b))
+
+	var entries Entries[int, bool]
+	assert.NoError(t, json.Unmarshal(b, &entries))
+	assert.Equal(t, m.GetAll(), entries)
+}
+
+func TestEntries_UnmarshalJSON(t *testing.T) {
+	var entries Entries[string, string]
+
+	err := json.Unmarshal([]byte(`{"key8":"value8","key1":"value1","key3":"value3"}`), &entries)
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("key8", "value8", "key1", "value1", "key3", "value3"), entries)
+
+	err = json.Unmarshal([]byte(`null`), &entries)
+	assert.NoError(t, err)
+	assert.Nil(t, entries)
+
+	err = json.Unmarshal([]byte(`["key8"]`), &entries)
+	assert.Error(t, err)
+}
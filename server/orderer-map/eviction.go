@@ -0,0 +1,107 @@
+package orderedmap
+
+import "fmt"
+
+// EvictionPolicy selects the item evicted when the map is full.
+type EvictionPolicy int
+
+const (
+	// FIFO evicts the oldest added item, the head of the list
+	FIFO EvictionPolicy = iota
+	// LRU evicts the least recently used item. Get and Add move the item to the tail,
+	// so the head is always the least recently used one.
+	LRU
+)
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case FIFO:
+		return "fifo"
+	case LRU:
+		return "lru"
+	}
+	return fmt.Sprintf("EvictionPolicy(%d)", int(p))
+}
+
+// ParseEvictionPolicy parses the policy name: fifo or lru.
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	switch s {
+	case "fifo":
+		return FIFO, nil
+	case "lru":
+		return LRU, nil
+	}
+	return 0, fmt.Errorf("unknown eviction policy %q, must be fifo or lru", s)
+}
+
+// WithCapacity limits the number of the items. Adding a new key to the full map
+// evicts the head item first. Zero means no limit, but with LRU the accessed
+// items are still moved to the tail.
+func WithCapacity(capacity int, policy EvictionPolicy) Option {
+	return func(c *config) {
+		c.capacity = capacity
+		c.policy = policy
+	}
+}
+
+// Capacity returns the maximum number of the items, zero if there is no limit.
+func (m *OrderedMap[K, V]) Capacity() int {
+	return m.capacity
+}
+
+// EvictionPolicy returns the policy the items are evicted and ordered with.
+func (m *OrderedMap[K, V]) EvictionPolicy() EvictionPolicy {
+	return m.policy
+}
+
+// Peek returns the value of the item like Get, but it's not counted as an access by LRU.
+func (m *OrderedMap[K, V]) Peek(key K) (V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node, exists := m.items[key]
+	if exists && !node.expired(m.now()) {
+		return node.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Touch moves the item to the tail as the most recently used one, whatever
+// the policy is and even if it's expired. It returns false if the key doesn't exist.
+func (m *OrderedMap[K, V]) Touch(key K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.items[key]
+	if exists && node != m.tail {
+		m.record(key, node)
+		m.moveToTail(node)
+	}
+	return exists
+}
+
+// moveToTail relinks the node to the tail of the list.
+func (m *OrderedMap[K, V]) moveToTail(node *node[K, V]) {
+	if node == m.tail {
+		return
+	}
+	m.detach(node)
+	m.link(node, nil)
+}
+
+// evict removes the head items until there is room for a new one. The keep node,
+// the position the new item is inserted at, is evicted only if it's the last one.
+func (m *OrderedMap[K, V]) evict(keep *node[K, V]) Entries[K, V] {
+	var evicted Entries[K, V]
+	for m.capacity > 0 && len(m.items) >= m.capacity {
+		node := m.head
+		if node == keep && node.next != nil {
+			node = node.next
+		}
+		m.unlink(node)
+		m.publish(Evicted, node.key, node.value)
+		evicted = append(evicted, Entry[K, V]{Key: node.key, Value: node.value})
+	}
+	return evicted
+}
@@ -0,0 +1,75 @@
+package orderedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap_EvictFIFO(t *testing.T) {
+	m := NewOrderedMap(WithCapacity(2, FIFO))
+
+	assert.Empty(t, m.Add("a", "1"))
+	assert.Empty(t, m.Add("b", "2"))
+	// The access doesn't change the order
+	m.Get("a")
+	// The update of the existing key doesn't evict
+	assert.Empty(t, m.Add("a", "3"))
+	assert.Equal(t, pairs("a", "3"), m.Add("c", "4"))
+	assert.Equal(t, pairs("b", "2", "c", "4"), m.GetAll())
+}
+
+func TestOrderedMap_EvictLRU(t *testing.T) {
+	m := NewOrderedMap(WithCapacity(3, LRU))
+
+	s, err := m.Subscribe(0, nil)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	m.Add("a", "1")
+	m.Add("b", "2")
+	m.Add("c", "3")
+
+	// The accessed and updated items become the most recently used
+	m.Get("a")
+	m.Add("b", "4")
+	assert.Equal(t, pairs("c", "3", "a", "1", "b", "4"), m.GetAll())
+
+	// Peek doesn't count as an access
+	m.Peek("c")
+	assert.Equal(t, pairs("c", "3"), m.Add("d", "5"))
+	assert.Equal(t, pairs("a", "1", "b", "4", "d", "5"), m.GetAll())
+
+	assert.True(t, m.Touch("a"))
+	assert.False(t, m.Touch("c"))
+	assert.Equal(t, pairs("b", "4", "d", "5", "a", "1"), m.GetAll())
+
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 1, Type: Added, Key: "a", Value: "1"},
+		{Seq: 2, Type: Added, Key: "b", Value: "2"},
+		{Seq: 3, Type: Added, Key: "c", Value: "3"},
+		// The access changed the revision 4 without an event
+		{Seq: 5, Type: Updated, Key: "b", Value: "4"},
+		{Seq: 6, Type: Evicted, Key: "c", Value: "3"},
+		{Seq: 7, Type: Added, Key: "d", Value: "5"},
+	}, receive(s))
+}
+
+func TestOrderedMap_LRUWithoutCapacity(t *testing.T) {
+	m := NewOrderedMap(WithCapacity(0, LRU))
+	for i, key := range []string{"a", "b", "c", "d"} {
+		assert.Empty(t, m.Add(key, string(rune('1'+i))))
+	}
+	m.Get("b")
+	assert.Equal(t, pairs("a", "1", "c", "3", "d", "4", "b", "2"), m.GetAll())
+}
+
+func TestParseEvictionPolicy(t *testing.T) {
+	for _, policy := range []EvictionPolicy{FIFO, LRU} {
+		parsed, err := ParseEvictionPolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+	_, err := ParseEvictionPolicy("random")
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package orderedmap
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// AddOption configures the added item.
+type AddOption func(*addOptions)
+
+type addOptions struct {
+	expiresAt time.Time
+	version   uint64
+}
+
+func newAddOptions(opts []AddOption) addOptions {
+	var o addOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ExpireAt makes the item expire at the time. The zero time means the item never expires.
+func ExpireAt(at time.Time) AddOption {
+	return func(o *addOptions) {
+		o.expiresAt = at
+	}
+}
+
+// WithClock sets the source of the current time the expiry is checked against.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+func (n *node[K, V]) expired(now time.Time) bool {
+	return !n.expiresAt.IsZero() && !now.Before(n.expiresAt)
+}
+
+// NextExpiry returns the earliest expiry time of the items, false if no item expires.
+func (m *OrderedMap[K, V]) NextExpiry() (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.expiries) == 0 {
+		return time.Time{}, false
+	}
+	return m.expiries[0].expiresAt, true
+}
+
+// Expired returns the items expired by the time in the expiry order. The expired
+// items are hidden from Get and GetAll, but they are kept until Expire removes them.
+func (m *OrderedMap[K, V]) Expired(now time.Time) Entries[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// The heap order isn't the expiry one, so the due nodes are sorted
+	due := m.due(now)
+	sort.SliceStable(due, func(i, j int) bool { return due[i].expiresAt.Before(due[j].expiresAt) })
+
+	result := make(Entries[K, V], 0, len(due))
+	for _, node := range due {
+		result = append(result, Entry[K, V]{Key: node.key, Value: node.value})
+	}
+	return result
+}
+
+// due returns the expired nodes which aren't removed yet. The children of a node in the
+// heap don't expire before it, so only the due nodes and their children are visited.
+func (m *OrderedMap[K, V]) due(now time.Time) []*node[K, V] {
+	var due []*node[K, V]
+	for stack := []int{0}; len(stack) > 0; {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if i >= len(m.expiries) || !m.expiries[i].expired(now) {
+			continue
+		}
+		due = append(due, m.expiries[i])
+		stack = append(stack, 2*i+1, 2*i+2)
+	}
+	return due
+}
+
+// Expire removes the item if it's expired. It returns false if the key doesn't exist or isn't expired.
+func (m *OrderedMap[K, V]) Expire(key K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.items[key]
+	if !exists || !node.expired(m.now()) {
+		return false
+	}
+	m.unlink(node)
+	m.publish(Expired, key, node.value)
+	return true
+}
+
+// Expiries returns the expiry times of the items which have them.
+func (m *OrderedMap[K, V]) Expiries() map[K]time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	result := make(map[K]time.Time, len(m.expiries))
+	for _, node := range m.expiries {
+		if !node.expired(now) {
+			result[node.key] = node.expiresAt
+		}
+	}
+	return result
+}
+
+// setExpiry updates the expiry time of the node and its position in the expiry queue.
+func (m *OrderedMap[K, V]) setExpiry(node *node[K, V], at time.Time) {
+	node.expiresAt = at
+	switch {
+	case at.IsZero() && node.heapIndex >= 0:
+		heap.Remove(&m.expiries, node.heapIndex)
+	case at.IsZero():
+	case node.heapIndex >= 0:
+		heap.Fix(&m.expiries, node.heapIndex)
+	default:
+		heap.Push(&m.expiries, node)
+	}
+}
+
+// expiryQueue is a min-heap of the nodes by the expiry time.
+type expiryQueue[K comparable, V any] []*node[K, V]
+
+func (q expiryQueue[K, V]) Len() int { return len(q) }
+
+func (q expiryQueue[K, V]) Less(i, j int) bool {
+	return q[i].expiresAt.Before(q[j].expiresAt)
+}
+
+func (q expiryQueue[K, V]) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].heapIndex = i
+	q[j].heapIndex = j
+}
+
+func (q *expiryQueue[K, V]) Push(x any) {
+	n := x.(*node[K, V])
+	n.heapIndex = len(*q)
+	*q = append(*q, n)
+}
+
+func (q *expiryQueue[K, V]) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	n.heapIndex = -1
+	return n
+}
@@ -0,0 +1,108 @@
+package orderedmap
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap_Expiry(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewOrderedMap(WithClock(func() time.Time { return now }))
+
+	m.Add("a", "1", ExpireAt(now.Add(2*time.Second)))
+	m.Add("b", "2")
+	m.Add("c", "3", ExpireAt(now.Add(time.Second)))
+
+	next, ok := m.NextExpiry()
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(time.Second), next)
+	assert.Equal(t, map[string]time.Time{"a": now.Add(2 * time.Second), "c": now.Add(time.Second)}, m.Expiries())
+
+	// The expired items are hidden before they are removed.
+	now = now.Add(2 * time.Second)
+	_, ok = m.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, pairs("b", "2"), m.GetAll())
+	assert.Equal(t, pairs("c", "3", "a", "1"), m.Expired(now))
+
+	assert.True(t, m.Expire("c"))
+	assert.False(t, m.Expire("c"))
+	assert.False(t, m.Expire("b"))
+	assert.Equal(t, pairs("a", "1"), m.Expired(now))
+
+	// The expired item which isn't removed yet is renewed in place.
+	m.Add("a", "4")
+	assert.Equal(t, pairs("a", "4", "b", "2"), m.GetAll())
+	_, ok = m.NextExpiry()
+	assert.False(t, ok)
+}
+
+func TestOrderedMap_ExpiryUpdate(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewOrderedMap(WithClock(func() time.Time { return now }))
+
+	s, err := m.Subscribe(0, nil)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	m.Add("a", "1", ExpireAt(now.Add(time.Second)))
+	m.Add("b", "2", ExpireAt(now.Add(time.Second)))
+	// Updating the item without TTL makes it persistent.
+	m.Add("a", "3")
+	// Updating the item with TTL moves its expiry time.
+	m.Add("b", "4", ExpireAt(now.Add(3*time.Second)))
+
+	now = now.Add(2 * time.Second)
+	assert.Empty(t, m.Expired(now))
+	assert.Equal(t, pairs("a", "3", "b", "4"), m.GetAll())
+
+	now = now.Add(time.Second)
+	assert.False(t, m.Remove("b"))
+	assert.Equal(t, pairs("a", "3"), m.GetAll())
+
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 1, Type: Added, Key: "a", Value: "1"},
+		{Seq: 2, Type: Added, Key: "b", Value: "2"},
+		{Seq: 3, Type: Updated, Key: "a", Value: "3"},
+		{Seq: 4, Type: Updated, Key: "b", Value: "4"},
+		{Seq: 5, Type: Expired, Key: "b", Value: "4"},
+	}, receive(s))
+}
+
+func TestOrderedMap_ExpiredRandom(t *testing.T) {
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	m := NewOrderedMap(WithClock(func() time.Time { return now }))
+	rnd := rand.New(rand.NewSource(1))
+	expiries := make(map[string]time.Time)
+	for i := 0; i < 500; i++ {
+		key := strconv.Itoa(i)
+		at := start.Add(time.Duration(rnd.Intn(100)) * time.Second)
+		m.Add(key, key, ExpireAt(at))
+		expiries[key] = at
+	}
+	m.Add("never", "never")
+
+	for _, now = range []time.Time{start, start.Add(10 * time.Second), start.Add(50 * time.Second), start.Add(time.Hour)} {
+		var expected []time.Time
+		for _, at := range expiries {
+			if !now.Before(at) {
+				expected = append(expected, at)
+			}
+		}
+		sort.Slice(expected, func(i, j int) bool { return expected[i].Before(expected[j]) })
+
+		expired := m.Expired(now)
+		var actual []time.Time
+		for _, item := range expired {
+			actual = append(actual, expiries[item.Key])
+		}
+		assert.Equal(t, expected, actual, "expired at %s", now)
+		assert.Equal(t, len(expiries)+1-len(expected), m.Len())
+	}
+}
@@ -0,0 +1,223 @@
+package orderedmap
+
+import "time"
+
+// The items are also kept in a treap ordered by their position in the list.
+// Every node counts the items of its subtree, so the position of an item and
+// the item at a position are found in O(log n) on average.
+
+// treeLinks are the treap fields of the node.
+type treeLinks[K comparable, V any] struct {
+	parent, left, right *node[K, V]
+	// size is the number of the nodes in the subtree
+	size int
+	// priority keeps the treap balanced, a parent has a higher one than its children
+	priority uint64
+}
+
+// At returns the item at the position in insertion order. It returns false if
+// the position is out of range. With expired items which aren't removed yet the
+// position is found by walking the list.
+func (m *OrderedMap[K, V]) At(i int) (Entry[K, V], bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node := m.nodeAt(i, m.now())
+	if node == nil {
+		return Entry[K, V]{}, false
+	}
+	return Entry[K, V]{Key: node.key, Value: node.value}, true
+}
+
+// IndexOf returns the position of the item in insertion order, -1 if the key doesn't exist.
+func (m *OrderedMap[K, V]) IndexOf(key K) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	node, exists := m.items[key]
+	if !exists || node.expired(now) {
+		return -1
+	}
+	if !m.hasExpired(now) {
+		return rank(node)
+	}
+	index := 0
+	for n := m.head; n != node; n = n.next {
+		if !n.expired(now) {
+			index++
+		}
+	}
+	return index
+}
+
+// Slice returns the items at the positions from the from one up to the to one, excluding it.
+func (m *OrderedMap[K, V]) Slice(from, to int) Entries[K, V] {
+	if from < 0 {
+		from = 0
+	}
+	if to <= from {
+		return Entries[K, V]{}
+	}
+	page, _ := m.Page(from, to-from, false)
+	return page
+}
+
+// nodeAt returns the node at the position of the not expired items, nil if it's out of range.
+func (m *OrderedMap[K, V]) nodeAt(i int, now time.Time) *node[K, V] {
+	if i < 0 {
+		return nil
+	}
+	if !m.hasExpired(now) {
+		return selectNode(m.root, i)
+	}
+	for node := m.head; node != nil; node = node.next {
+		if node.expired(now) {
+			continue
+		}
+		if i == 0 {
+			return node
+		}
+		i--
+	}
+	return nil
+}
+
+// hasExpired reports whether there are expired items which aren't removed yet.
+func (m *OrderedMap[K, V]) hasExpired(now time.Time) bool {
+	return len(m.expiries) > 0 && m.expiries[0].expired(now)
+}
+
+func size[K comparable, V any](n *node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// rank returns the position of the node in the treap.
+func rank[K comparable, V any](n *node[K, V]) int {
+	r := size(n.left)
+	for ; n.parent != nil; n = n.parent {
+		if n == n.parent.right {
+			r += size(n.parent.left) + 1
+		}
+	}
+	return r
+}
+
+// selectNode returns the node at the position in the subtree, nil if it's out of range.
+func selectNode[K comparable, V any](n *node[K, V], i int) *node[K, V] {
+	for n != nil {
+		left := size(n.left)
+		switch {
+		case i < left:
+			n = n.left
+		case i == left:
+			return n
+		default:
+			i -= left + 1
+			n = n.right
+		}
+	}
+	return nil
+}
+
+// indexInsert adds the node to the treap right before the mark node, at the end if the mark is nil.
+func (m *OrderedMap[K, V]) indexInsert(n, mark *node[K, V]) {
+	n.parent, n.left, n.right, n.size = nil, nil, nil, 1
+	n.priority = m.nextPriority()
+
+	// The list isn't changed yet, the tail and the node preceding
+	// the mark in the list are the rightmost ones in their subtrees
+	switch {
+	case m.root == nil:
+		m.root = n
+		return
+	case mark == nil:
+		m.tail.right = n
+		n.parent = m.tail
+	case mark.left == nil:
+		mark.left = n
+		n.parent = mark
+	default:
+		mark.prev.right = n
+		n.parent = mark.prev
+	}
+	for p := n.parent; p != nil; p = p.parent {
+		p.size++
+	}
+
+	for n.parent != nil && n.parent.priority < n.priority {
+		m.rotateUp(n)
+	}
+}
+
+// indexRemove removes the node from the treap.
+func (m *OrderedMap[K, V]) indexRemove(n *node[K, V]) {
+	// Rotate the node down until it has at most one child
+	for n.left != nil && n.right != nil {
+		if n.left.priority > n.right.priority {
+			m.rotateUp(n.left)
+		} else {
+			m.rotateUp(n.right)
+		}
+	}
+
+	child := n.left
+	if child == nil {
+		child = n.right
+	}
+	m.replace(n, child)
+	for p := n.parent; p != nil; p = p.parent {
+		p.size--
+	}
+	n.parent, n.left, n.right, n.size = nil, nil, nil, 0
+}
+
+// rotateUp moves the node above its parent keeping the order of the nodes.
+func (m *OrderedMap[K, V]) rotateUp(n *node[K, V]) {
+	p := n.parent
+	m.replace(p, n)
+	if n == p.left {
+		p.left = n.right
+		if n.right != nil {
+			n.right.parent = p
+		}
+		n.right = p
+	} else {
+		p.right = n.left
+		if n.left != nil {
+			n.left.parent = p
+		}
+		n.left = p
+	}
+	p.parent = n
+
+	p.size = size(p.left) + size(p.right) + 1
+	n.size = size(n.left) + size(n.right) + 1
+}
+
+// replace puts the with node to the place of the n node under its parent.
+func (m *OrderedMap[K, V]) replace(n, with *node[K, V]) {
+	parent := n.parent
+	switch {
+	case parent == nil:
+		m.root = with
+	case parent.left == n:
+		parent.left = with
+	default:
+		parent.right = with
+	}
+	if with != nil {
+		with.parent = parent
+	}
+}
+
+// nextPriority returns a pseudo-random priority generated by xorshift.
+func (m *OrderedMap[K, V]) nextPriority() uint64 {
+	m.rng ^= m.rng << 13
+	m.rng ^= m.rng >> 7
+	m.rng ^= m.rng << 17
+	return m.rng
+}
@@ -0,0 +1,195 @@
+package orderedmap
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedMap_AtAndIndexOf(t *testing.T) {
+	m := NewOrderedMap()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Add(key, key+"1")
+	}
+	m.Remove("b")
+	m.MoveToFront("d")
+
+	entry, ok := m.At(0)
+	assert.True(t, ok)
+	assert.Equal(t, Entry[string, string]{Key: "d", Value: "d1"}, entry)
+	entry, ok = m.At(2)
+	assert.True(t, ok)
+	assert.Equal(t, "c", entry.Key)
+	_, ok = m.At(3)
+	assert.False(t, ok)
+	_, ok = m.At(-1)
+	assert.False(t, ok)
+
+	assert.Equal(t, 1, m.IndexOf("a"))
+	assert.Equal(t, -1, m.IndexOf("b"))
+	assert.Equal(t, pairs("a", "a1", "c", "c1"), m.Slice(1, 5))
+	assert.Empty(t, m.Slice(2, 1))
+}
+
+func TestOrderedMap_IndexExpired(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewOrderedMap(WithClock(func() time.Time { return now }))
+	m.Add("a", "1")
+	m.Add("b", "2", ExpireAt(now.Add(time.Second)))
+	m.Add("c", "3")
+	now = now.Add(time.Second)
+
+	// The expired item isn't counted
+	entry, _ := m.At(1)
+	assert.Equal(t, "c", entry.Key)
+	assert.Equal(t, 1, m.IndexOf("c"))
+	assert.Equal(t, -1, m.IndexOf("b"))
+}
+
+// TestOrderedMap_IndexRandom applies random operations and checks the index against the list.
+func TestOrderedMap_IndexRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	m := NewOrderedMap(WithCapacity(300, LRU))
+
+	for i := 0; i < 5000; i++ {
+		key := strconv.Itoa(rnd.Intn(400))
+		mark := strconv.Itoa(rnd.Intn(400))
+		switch rnd.Intn(8) {
+		case 0, 1:
+			m.Add(key, key)
+		case 2:
+			m.Remove(key)
+		case 3:
+			m.Get(key)
+		case 4:
+			m.InsertBefore(mark, key, key)
+		case 5:
+			m.InsertAfter(mark, key, key)
+		case 6:
+			m.MoveToFront(key)
+		case 7:
+			m.MoveToBack(key)
+		}
+		if i%500 == 0 {
+			checkIndex(t, m)
+		}
+	}
+	checkIndex(t, m)
+}
+
+// checkIndex verifies the treap invariants and the positions of all the items.
+func checkIndex(t *testing.T, m *StringMap) {
+	t.Helper()
+
+	var walk func(n *node[string, string]) int
+	walk = func(n *node[string, string]) int {
+		if n == nil {
+			return 0
+		}
+		for _, child := range []*node[string, string]{n.left, n.right} {
+			if child != nil {
+				require.Same(t, n, child.parent, "parent of %s", child.key)
+				require.LessOrEqual(t, child.priority, n.priority, "priority of %s", child.key)
+			}
+		}
+		count := walk(n.left) + walk(n.right) + 1
+		require.Equal(t, count, n.size, "size of %s", n.key)
+		return count
+	}
+	require.Equal(t, len(m.items), walk(m.root))
+
+	for i, entry := range m.GetAll() {
+		require.Equal(t, i, m.IndexOf(entry.Key))
+		at, ok := m.At(i)
+		require.True(t, ok)
+		require.Equal(t, entry, at)
+	}
+}
+
+// The benchmarks compare the position index with walking the list,
+// which was the only way to find the positions before the index.
+
+const benchmarkSize = 100000
+
+func benchmarkMap() *StringMap {
+	m := NewOrderedMap()
+	for i := 0; i < benchmarkSize; i++ {
+		m.Add(strconv.Itoa(i), strconv.Itoa(i))
+	}
+	return m
+}
+
+// atByWalk finds the item at the position by walking the list.
+func atByWalk(m *StringMap, i int) (Entry[string, string], bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for node := m.head; node != nil; node = node.next {
+		if i == 0 {
+			return Entry[string, string]{Key: node.key, Value: node.value}, true
+		}
+		i--
+	}
+	return Entry[string, string]{}, false
+}
+
+// indexOfByWalk finds the position of the key by walking the list.
+func indexOfByWalk(m *StringMap, key string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index := 0
+	for node := m.head; node != nil; node = node.next {
+		if node.key == key {
+			return index
+		}
+		index++
+	}
+	return -1
+}
+
+func BenchmarkAt(b *testing.B) {
+	m := benchmarkMap()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.At(i % benchmarkSize)
+	}
+}
+
+func BenchmarkAtByWalk(b *testing.B) {
+	m := benchmarkMap()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		atByWalk(m, i%benchmarkSize)
+	}
+}
+
+func BenchmarkIndexOf(b *testing.B) {
+	m := benchmarkMap()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.IndexOf(strconv.Itoa(i % benchmarkSize))
+	}
+}
+
+func BenchmarkIndexOfByWalk(b *testing.B) {
+	m := benchmarkMap()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		indexOfByWalk(m, strconv.Itoa(i%benchmarkSize))
+	}
+}
+
+func BenchmarkAddRemove(b *testing.B) {
+	m := benchmarkMap()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := strconv.Itoa(i % benchmarkSize)
+		m.Remove(key)
+		m.Add(key, key)
+	}
+}
@@ -2,89 +2,247 @@ package orderedmap
 
 import (
 	"sync"
+	"time"
 )
 
-type node struct {
-	key   string
-	value string
-	prev  *node
-	next  *node
+type node[K comparable, V any] struct {
+	key   K
+	value V
+	prev  *node[K, V]
+	next  *node[K, V]
+	// Position index, see index.go
+	treeLinks[K, V]
+	// expiresAt is zero if the item never expires
+	expiresAt time.Time
+	// heapIndex is the position in the expiry queue, -1 if the item isn't there
+	heapIndex int
+	// version is the revision the item was added at plus the number of its updates, see conditional.go
+	version uint64
 }
 
-type OrderedMap struct {
-	items map[string]*node
-	head  *node
-	tail  *node
+// OrderedMap is a concurrency safe map which keeps the insertion order of the keys.
+type OrderedMap[K comparable, V any] struct {
+	items map[K]*node[K, V]
+	head  *node[K, V]
+	tail  *node[K, V]
 	mu    sync.RWMutex
+
+	// Change notifications, see watch.go. The changes up to the compacted
+	// revision aren't kept in the history.
+	compacted   uint64
+	history     []Event[K, V]
+	historySize int
+	subscribers map[*Subscription[K, V]]struct{}
+
+	// Items with TTL ordered by the expiry time, see expiry.go
+	expiries expiryQueue[K, V]
+	now      func() time.Time
+
+	// Position index of the items, see index.go
+	root *node[K, V]
+	rng  uint64
+
+	// Maximum number of the items, see eviction.go
+	capacity int
+	policy   EvictionPolicy
+
+	// Changes of the latest revisions, see revision.go
+	revision  uint64
+	changes   []change[K, V]
+	retention int
+
+	// The open batch of the changes, see batch.go
+	batch *batch[K, V]
+}
+
+// Option configures the OrderedMap.
+type Option func(*config)
+
+type config struct {
+	historySize int
+	now         func() time.Time
+	capacity    int
+	policy      EvictionPolicy
+	retention   int
 }
 
-func NewOrderedMap() *OrderedMap {
-	return &OrderedMap{
-		items: make(map[string]*node),
+// StringMap is the ordered map of string keys and values.
+type StringMap = OrderedMap[string, string]
+
+func New[K comparable, V any](opts ...Option) *OrderedMap[K, V] {
+	cfg := config{historySize: DefaultHistorySize, now: time.Now, retention: DefaultRetention}
+	for _, opt := range opts {
+		opt(&cfg)
 	}
+
+	return &OrderedMap[K, V]{
+		items:       make(map[K]*node[K, V]),
+		historySize: cfg.historySize,
+		subscribers: make(map[*Subscription[K, V]]struct{}),
+		now:         cfg.now,
+		capacity:    cfg.capacity,
+		policy:      cfg.policy,
+		retention:   cfg.retention,
+		rng:         0x9e3779b97f4a7c15,
+	}
+}
+
+func NewOrderedMap(opts ...Option) *StringMap {
+	return New[string, string](opts...)
 }
 
-func (m *OrderedMap) Add(key, value string) {
+// Add appends the item to the tail or updates the value of the existing one in place,
+// with LRU the updated item is moved to the tail. Updating the item resets its expiry
+// time to the one of the options, so the expired item which isn't removed yet is renewed,
+// and increments its version. If the map is full, the items evicted to make room for
+// the new key are returned.
+func (m *OrderedMap[K, V]) Add(key K, value V, opts ...AddOption) Entries[K, V] {
+	o := newAddOptions(opts)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.items[key]; !exists {
-		newNode := &node{
-			key:   key,
-			value: value,
-			prev:  m.tail,
-		}
-		if m.tail != nil {
-			m.tail.next = newNode
-		} else {
-			m.head = newNode
-		}
-		m.tail = newNode
-		m.items[key] = newNode
-	} else {
-		m.items[key].value = value
+	if node, exists := m.items[key]; exists {
+		m.update(node, value, o)
+		return nil
 	}
+	evicted := m.evict(nil)
+	m.insert(key, value, o, nil)
+	return evicted
 }
 
-func (m *OrderedMap) Remove(key string) bool {
+// Remove removes the item. It returns false if the key doesn't exist or is expired.
+func (m *OrderedMap[K, V]) Remove(key K) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if node, exists := m.items[key]; exists {
-		if node.prev != nil {
-			node.prev.next = node.next
-		} else {
-			m.head = node.next
-		}
-		if node.next != nil {
-			node.next.prev = node.prev
-		} else {
-			m.tail = node.prev
+		expired := node.expired(m.now())
+		m.unlink(node)
+		if expired {
+			m.publish(Expired, key, node.value)
+			return false
 		}
-		delete(m.items, key)
+		m.publish(Removed, key, node.value)
 		return exists
 	}
 	return false
 }
 
-func (m *OrderedMap) Get(key string) (string, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// insert links the new item before the mark node, at the tail if the mark is nil.
+func (m *OrderedMap[K, V]) insert(key K, value V, o addOptions, mark *node[K, V]) {
+	m.record(key, nil)
+	newNode := &node[K, V]{
+		key:       key,
+		value:     value,
+		heapIndex: -1,
+		version:   m.revision,
+	}
+	if o.version > 0 {
+		newNode.version = o.version
+	}
+	m.link(newNode, mark)
+	m.items[key] = newNode
+	m.setExpiry(newNode, o.expiresAt)
+	m.publish(Added, key, value)
+}
+
+// update sets the value and the expiry time of the existing item and increments its version.
+// With LRU the item is moved to the tail.
+func (m *OrderedMap[K, V]) update(node *node[K, V], value V, o addOptions) {
+	m.record(node.key, node)
+	node.value = value
+	node.version++
+	m.setExpiry(node, o.expiresAt)
+	if m.policy == LRU {
+		m.moveToTail(node)
+	}
+	m.publish(Updated, node.key, value)
+}
+
+// unlink removes the node from the list, the index and the expiry queue.
+func (m *OrderedMap[K, V]) unlink(node *node[K, V]) {
+	m.record(node.key, node)
+	m.detach(node)
+	delete(m.items, node.key)
+	m.setExpiry(node, time.Time{})
+}
+
+// link puts the detached node before the mark node, at the tail if the mark is nil.
+func (m *OrderedMap[K, V]) link(node, mark *node[K, V]) {
+	m.indexInsert(node, mark)
+	if mark == nil {
+		node.prev = m.tail
+		node.next = nil
+		if m.tail != nil {
+			m.tail.next = node
+		} else {
+			m.head = node
+		}
+		m.tail = node
+		return
+	}
+
+	node.prev = mark.prev
+	node.next = mark
+	if mark.prev != nil {
+		mark.prev.next = node
+	} else {
+		m.head = node
+	}
+	mark.prev = node
+}
+
+// detach removes the node from the list and the position index.
+func (m *OrderedMap[K, V]) detach(node *node[K, V]) {
+	m.indexRemove(node)
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		m.head = node.next
+	}
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		m.tail = node.prev
+	}
+	node.prev = nil
+	node.next = nil
+}
+
+// Get returns the value of the item. With LRU the item is moved to the tail.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if m.policy != LRU {
+		return m.Peek(key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	node, exists := m.items[key]
-	if exists {
+	if exists && !node.expired(m.now()) {
+		if node != m.tail {
+			m.record(key, node)
+			m.moveToTail(node)
+		}
 		return node.value, true
 	}
-	return "", false
+	var zero V
+	return zero, false
 }
 
-func (m *OrderedMap) GetAll() []string {
+// GetAll returns all the items in insertion order.
+func (m *OrderedMap[K, V]) GetAll() Entries[K, V] {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	result := make([]string, 0, len(m.items))
+	now := m.now()
+	result := make(Entries[K, V], 0, len(m.items))
 	for node := m.head; node != nil; node = node.next {
-		result = append(result, node.key+"="+node.value)
+		if node.expired(now) {
+			continue
+		}
+		result = append(result, Entry[K, V]{Key: node.key, Value: node.value})
 	}
 	return result
 }
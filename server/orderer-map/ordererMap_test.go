@@ -41,7 +41,7 @@ func TestGetAll(t *testing.T) {
 	m.Add("key1", "value1")
 	m.Add("key2", "value2")
 
-	expected := []string{"key1=value1", "key2=value2"}
+	expected := pairs("key1", "value1", "key2", "value2")
 	if result := m.GetAll(); !equal(result, expected) {
 		t.Errorf("m.GetAll() = %v, expected %v", result, expected)
 	}
@@ -56,17 +56,17 @@ func TestOrderedMap_GetAll(t *testing.T) {
 
 	// Get all items and check the order.
 	all := m.GetAll()
-	assert.Equal(t, []string{"a=1", "b=2", "c=3"}, all)
+	assert.Equal(t, pairs("a", "1", "b", "2", "c", "3"), all)
 
 	// Remove an item and check the order again.
 	m.Remove("b")
 	all = m.GetAll()
-	assert.Equal(t, []string{"a=1", "c=3"}, all)
+	assert.Equal(t, pairs("a", "1", "c", "3"), all)
 
 	// Add a new item and check the order again.
 	m.Add("d", "4")
 	all = m.GetAll()
-	assert.Equal(t, []string{"a=1", "c=3", "d=4"}, all)
+	assert.Equal(t, pairs("a", "1", "c", "3", "d", "4"), all)
 }
 
 func TestOrderedMap_Add(t *testing.T) {
@@ -77,15 +77,15 @@ func TestOrderedMap_Add(t *testing.T) {
 	m.Add("a", "1")
 	m.Add("b", "2")
 	m.Add("c", "3")
-	assert.Equal(t, []string{"a=1", "b=2", "c=3"}, m.GetAll())
+	assert.Equal(t, pairs("a", "1", "b", "2", "c", "3"), m.GetAll())
 
 	// Add an item that already exists and check the order.
 	m.Add("a", "4")
-	assert.Equal(t, []string{"a=4", "b=2", "c=3"}, m.GetAll())
+	assert.Equal(t, pairs("a", "4", "b", "2", "c", "3"), m.GetAll())
 
 	// Add another item and check the order.
 	m.Add("d", "5")
-	assert.Equal(t, []string{"a=4", "b=2", "c=3", "d=5"}, m.GetAll())
+	assert.Equal(t, pairs("a", "4", "b", "2", "c", "3", "d", "5"), m.GetAll())
 }
 
 func TestOrderedMap_Remove(t *testing.T) {
@@ -98,22 +98,31 @@ func TestOrderedMap_Remove(t *testing.T) {
 
 	// Remove an item from the middle and check the order.
 	m.Remove("b")
-	assert.Equal(t, []string{"a=1", "c=3", "d=4"}, m.GetAll())
+	assert.Equal(t, pairs("a", "1", "c", "3", "d", "4"), m.GetAll())
 
 	// Remove the first item and check the order.
 	m.Remove("a")
-	assert.Equal(t, []string{"c=3", "d=4"}, m.GetAll())
+	assert.Equal(t, pairs("c", "3", "d", "4"), m.GetAll())
 
 	// Remove the last item and check the order.
 	m.Remove("d")
-	assert.Equal(t, []string{"c=3"}, m.GetAll())
+	assert.Equal(t, pairs("c", "3"), m.GetAll())
 
 	// Remove an item that doesn't exist and check the order.
 	m.Remove("e")
-	assert.Equal(t, []string{"c=3"}, m.GetAll())
+	assert.Equal(t, pairs("c", "3"), m.GetAll())
 }
 
-func equal(a, b []string) bool {
+// pairs builds the string entries from the key, value sequence.
+func pairs(kv ...string) Entries[string, string] {
+	result := Entries[string, string]{}
+	for i := 0; i < len(kv); i += 2 {
+		result = append(result, Entry[string, string]{Key: kv[i], Value: kv[i+1]})
+	}
+	return result
+}
+
+func equal(a, b Entries[string, string]) bool {
 	if len(a) != len(b) {
 		return false
 	}
@@ -124,3 +133,26 @@ func equal(a, b []string) bool {
 	}
 	return true
 }
+
+func TestOrderedMap_Generic(t *testing.T) {
+	type point struct{ X, Y int }
+
+	// Initialize the map with non-string keys and values.
+	m := New[int, point]()
+	m.Add(3, point{1, 2})
+	m.Add(1, point{3, 4})
+	m.Add(2, point{5, 6})
+
+	value, ok := m.Get(1)
+	assert.True(t, ok)
+	assert.Equal(t, point{3, 4}, value)
+
+	// Missing key returns the zero value.
+	value, ok = m.Get(4)
+	assert.False(t, ok)
+	assert.Equal(t, point{}, value)
+
+	// Remove an item and check the insertion order is kept.
+	assert.True(t, m.Remove(1))
+	assert.Equal(t, Entries[int, point]{{3, point{1, 2}}, {2, point{5, 6}}}, m.GetAll())
+}
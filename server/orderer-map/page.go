@@ -0,0 +1,118 @@
+package orderedmap
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCursorNotFound is returned when the key to continue the page after doesn't exist.
+var ErrCursorNotFound = errors.New("the cursor key doesn't exist")
+
+// Len returns the number of the items, the expired ones aren't counted. It takes O(1)
+// time plus the number of the expired items which aren't removed yet.
+func (m *OrderedMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.items) - len(m.due(m.now()))
+}
+
+// Page returns up to limit items starting from the offset in insertion order,
+// or in the reverse order from the tail if reverse is set. The more result
+// reports whether there are items after the page. Zero limit means no limit.
+// The start of the page is found by the position index.
+func (m *OrderedMap[K, V]) Page(offset, limit int, reverse bool) (page Entries[K, V], more bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	var node *node[K, V]
+	switch {
+	case offset < 0:
+		node = m.first(reverse)
+	case !m.hasExpired(now) && reverse:
+		node = selectNode(m.root, size(m.root)-1-offset)
+	case !m.hasExpired(now):
+		node = selectNode(m.root, offset)
+	default:
+		// The expired items aren't counted, so they are skipped one by one
+		node = m.first(reverse)
+		for ; node != nil && offset > 0; node = step(node, reverse) {
+			if !node.expired(now) {
+				offset--
+			}
+		}
+	}
+	return m.collect(node, limit, reverse, now)
+}
+
+// PageAfter returns up to limit items following the cursor key in insertion order,
+// or preceding it in the reverse order, so the last key of a page is the cursor of
+// the next one. The cursor may be an expired item which isn't removed yet.
+func (m *OrderedMap[K, V]) PageAfter(cursor K, limit int, reverse bool) (page Entries[K, V], more bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node, exists := m.items[cursor]
+	if !exists {
+		return nil, false, ErrCursorNotFound
+	}
+	page, more = m.collect(step(node, reverse), limit, reverse, m.now())
+	return page, more, nil
+}
+
+// Head returns the first n items in insertion order.
+func (m *OrderedMap[K, V]) Head(n int) Entries[K, V] {
+	page, _ := m.Page(0, n, false)
+	return page
+}
+
+// Tail returns the last n items in insertion order.
+func (m *OrderedMap[K, V]) Tail(n int) Entries[K, V] {
+	page, _ := m.Page(0, n, true)
+	for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+		page[i], page[j] = page[j], page[i]
+	}
+	return page
+}
+
+// first returns the node the iteration starts from.
+func (m *OrderedMap[K, V]) first(reverse bool) *node[K, V] {
+	if reverse {
+		return m.tail
+	}
+	return m.head
+}
+
+// step returns the next node in the iteration order.
+func step[K comparable, V any](node *node[K, V], reverse bool) *node[K, V] {
+	if node == nil {
+		return nil
+	}
+	if reverse {
+		return node.prev
+	}
+	return node.next
+}
+
+// collect copies up to limit not expired items starting from the node.
+func (m *OrderedMap[K, V]) collect(node *node[K, V], limit int, reverse bool, now time.Time) (Entries[K, V], bool) {
+	page := make(Entries[K, V], 0, pageCapacity(limit, len(m.items)))
+	for ; node != nil; node = step(node, reverse) {
+		if node.expired(now) {
+			continue
+		}
+		if limit > 0 && len(page) == limit {
+			return page, true
+		}
+		page = append(page, Entry[K, V]{Key: node.key, Value: node.value})
+	}
+	return page, false
+}
+
+func pageCapacity(limit, size int) int {
+	if limit > 0 && limit < size {
+		return limit
+	}
+	return size
+}
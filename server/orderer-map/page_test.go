@@ -0,0 +1,84 @@
+package orderedmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap_Page(t *testing.T) {
+	m := NewOrderedMap()
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		m.Add(key, key+"1")
+	}
+	assert.Equal(t, 5, m.Len())
+
+	page, more := m.Page(1, 2, false)
+	assert.Equal(t, pairs("b", "b1", "c", "c1"), page)
+	assert.True(t, more)
+
+	page, more = m.Page(3, 2, false)
+	assert.Equal(t, pairs("d", "d1", "e", "e1"), page)
+	assert.False(t, more)
+
+	page, more = m.Page(1, 0, true)
+	assert.Equal(t, pairs("d", "d1", "c", "c1", "b", "b1", "a", "a1"), page)
+	assert.False(t, more)
+
+	page, more = m.Page(10, 2, false)
+	assert.Empty(t, page)
+	assert.False(t, more)
+
+	assert.Equal(t, pairs("a", "a1", "b", "b1"), m.Head(2))
+	assert.Equal(t, pairs("d", "d1", "e", "e1"), m.Tail(2))
+	assert.Equal(t, m.GetAll(), m.Tail(10))
+}
+
+func TestOrderedMap_PageAfter(t *testing.T) {
+	m := NewOrderedMap()
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		m.Add(key, key+"1")
+	}
+
+	// Page through the map with the cursor
+	var all Entries[string, string]
+	page, more := m.Page(0, 2, false)
+	all = append(all, page...)
+	for more {
+		var err error
+		page, more, err = m.PageAfter(page[len(page)-1].Key, 2, false)
+		assert.NoError(t, err)
+		all = append(all, page...)
+	}
+	assert.Equal(t, m.GetAll(), all)
+
+	page, more, err := m.PageAfter("c", 5, true)
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("b", "b1", "a", "a1"), page)
+	assert.False(t, more)
+
+	// The removed cursor key is reported
+	m.Remove("c")
+	_, _, err = m.PageAfter("c", 2, false)
+	assert.ErrorIs(t, err, ErrCursorNotFound)
+}
+
+func TestOrderedMap_PageExpired(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewOrderedMap(WithClock(func() time.Time { return now }))
+	m.Add("a", "1")
+	m.Add("b", "2", ExpireAt(now.Add(time.Second)))
+	m.Add("c", "3")
+	m.Add("d", "4")
+	now = now.Add(time.Second)
+
+	assert.Equal(t, 3, m.Len())
+	page, more := m.Page(1, 1, false)
+	assert.Equal(t, pairs("c", "3"), page)
+	assert.True(t, more)
+	// The expired cursor still continues the page
+	page, _, err := m.PageAfter("b", 1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("c", "3"), page)
+}
@@ -0,0 +1,103 @@
+package orderedmap
+
+import "errors"
+
+// ErrMarkNotFound is returned when the item to insert the new one next to doesn't exist.
+var ErrMarkNotFound = errors.New("the mark key doesn't exist")
+
+// InsertBefore puts the item right before the mark item. The existing item is updated
+// like by Add and moved there. If the map is full, the items evicted to make room
+// for the new key are returned; the mark item is evicted only if it's the last one.
+// Like by Add, the expired items which aren't removed yet are handled as existing ones.
+func (m *OrderedMap[K, V]) InsertBefore(mark, key K, value V, opts ...AddOption) (Entries[K, V], error) {
+	return m.insertAt(mark, key, value, opts, false)
+}
+
+// InsertAfter puts the item right after the mark item, see InsertBefore.
+func (m *OrderedMap[K, V]) InsertAfter(mark, key K, value V, opts ...AddOption) (Entries[K, V], error) {
+	return m.insertAt(mark, key, value, opts, true)
+}
+
+// MoveToFront moves the item to the head. It returns false if the key doesn't exist.
+func (m *OrderedMap[K, V]) MoveToFront(key K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.items[key]
+	if !exists {
+		return false
+	}
+	if node != m.head {
+		m.record(key, node)
+		m.detach(node)
+		m.link(node, m.head)
+		m.publish(Moved, key, node.value)
+	}
+	return true
+}
+
+// MoveToBack moves the item to the tail. It returns false if the key doesn't exist.
+func (m *OrderedMap[K, V]) MoveToBack(key K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.items[key]
+	if !exists {
+		return false
+	}
+	if node != m.tail {
+		m.record(key, node)
+		m.moveToTail(node)
+		m.publish(Moved, key, node.value)
+	}
+	return true
+}
+
+func (m *OrderedMap[K, V]) insertAt(markKey, key K, value V, opts []AddOption, after bool) (Entries[K, V], error) {
+	o := newAddOptions(opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mark, exists := m.items[markKey]
+	if !exists {
+		return nil, ErrMarkNotFound
+	}
+
+	existing, exists := m.items[key]
+	if !exists {
+		evicted := m.evict(mark)
+		if _, exists := m.items[markKey]; !exists {
+			// The mark was the last item
+			m.insert(key, value, o, nil)
+		} else if after {
+			m.insert(key, value, o, mark.next)
+		} else {
+			m.insert(key, value, o, mark)
+		}
+		return evicted, nil
+	}
+
+	// The update and the move are separate changes, each has its own event
+	m.record(key, existing)
+	existing.value = value
+	existing.version++
+	m.setExpiry(existing, o.expiresAt)
+	m.publish(Updated, key, value)
+	if existing == mark {
+		return nil, nil
+	}
+	if after && mark.next == existing || !after && mark.prev == existing {
+		// Already in place
+		return nil, nil
+	}
+	m.record(key, existing)
+	m.detach(existing)
+	if after {
+		m.link(existing, mark.next)
+	} else {
+		m.link(existing, mark)
+	}
+	m.publish(Moved, key, value)
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+package orderedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap_Insert(t *testing.T) {
+	m := NewOrderedMap()
+	m.Add("a", "1")
+	m.Add("c", "3")
+
+	evicted, err := m.InsertBefore("c", "b", "2")
+	assert.NoError(t, err)
+	assert.Empty(t, evicted)
+	_, err = m.InsertBefore("a", "0", "0")
+	assert.NoError(t, err)
+	_, err = m.InsertAfter("c", "d", "4")
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("0", "0", "a", "1", "b", "2", "c", "3", "d", "4"), m.GetAll())
+
+	// The existing item is updated and moved
+	_, err = m.InsertAfter("a", "d", "5")
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("0", "0", "a", "1", "d", "5", "b", "2", "c", "3"), m.GetAll())
+	_, err = m.InsertBefore("0", "c", "6")
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("c", "6", "0", "0", "a", "1", "d", "5", "b", "2"), m.GetAll())
+
+	_, err = m.InsertBefore("x", "y", "7")
+	assert.ErrorIs(t, err, ErrMarkNotFound)
+	_, ok := m.Get("y")
+	assert.False(t, ok)
+}
+
+func TestOrderedMap_Move(t *testing.T) {
+	m := NewOrderedMap()
+	m.Add("a", "1")
+	m.Add("b", "2")
+	m.Add("c", "3")
+
+	s, err := m.Subscribe(m.Seq(), nil)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	assert.True(t, m.MoveToFront("c"))
+	assert.Equal(t, pairs("c", "3", "a", "1", "b", "2"), m.GetAll())
+	assert.True(t, m.MoveToBack("c"))
+	assert.Equal(t, pairs("a", "1", "b", "2", "c", "3"), m.GetAll())
+	// Moving the item to its own position isn't a change
+	assert.True(t, m.MoveToFront("a"))
+	assert.True(t, m.MoveToBack("c"))
+	assert.False(t, m.MoveToFront("x"))
+
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 4, Type: Moved, Key: "c", Value: "3"},
+		{Seq: 5, Type: Moved, Key: "c", Value: "3"},
+	}, receive(s))
+}
+
+func TestOrderedMap_InsertFull(t *testing.T) {
+	m := NewOrderedMap(WithCapacity(2, FIFO))
+	m.Add("a", "1")
+	m.Add("b", "2")
+
+	// The mark at the head isn't evicted
+	evicted, err := m.InsertBefore("a", "c", "3")
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("b", "2"), evicted)
+	assert.Equal(t, pairs("c", "3", "a", "1"), m.GetAll())
+
+	m = NewOrderedMap(WithCapacity(1, FIFO))
+	m.Add("a", "1")
+	evicted, err = m.InsertAfter("a", "b", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, pairs("a", "1"), evicted)
+	assert.Equal(t, pairs("b", "2"), m.GetAll())
+}
@@ -0,0 +1,332 @@
+package orderedmap
+
+import "errors"
+
+// Every change of an item, including its move, increments the revision of the map.
+// The state of the item before the change is kept for the latest revisions, so the
+// items can be read as they were at a past revision by undoing the later changes.
+
+// DefaultRetention is the number of the latest revisions the items can be read at.
+const DefaultRetention = 1024
+
+var (
+	// ErrRevisionCompacted is returned when the changes after the revision are no longer kept.
+	ErrRevisionCompacted = errors.New("the revision is no longer kept")
+	// ErrFutureRevision is returned when the revision wasn't reached yet.
+	ErrFutureRevision = errors.New("the revision is ahead of the map")
+)
+
+// change is the state of the item before the change made at the revision.
+type change[K comparable, V any] struct {
+	revision uint64
+	key      K
+	// existed is false if the item was added by the change
+	existed bool
+	value   V
+	// prev is the key of the item preceding the changed one, atHead is set if there was none
+	prev   K
+	atHead bool
+}
+
+// WithRetention sets the number of the latest revisions the items can be read at,
+// 0 to read the current revision only.
+func WithRetention(revisions int) Option {
+	return func(c *config) {
+		c.retention = revisions
+	}
+}
+
+// Revision returns the current revision, the number of the changes applied to the map.
+func (m *OrderedMap[K, V]) Revision() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.revision
+}
+
+// OldestRevision returns the oldest revision the items can be read at.
+func (m *OrderedMap[K, V]) OldestRevision() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.revision - uint64(len(m.changes))
+}
+
+// RestoreRevision sets the current revision and forgets the past ones with their
+// changes, e.g. after the map is restored from a snapshot taken at the revision.
+// The subscriptions can't be resumed after the past revisions anymore.
+func (m *OrderedMap[K, V]) RestoreRevision(revision uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revision = revision
+	m.changes = nil
+	m.history = nil
+	m.compacted = revision
+}
+
+// GetAtRevision returns the value the item had at the revision. The expiry isn't
+// checked, the expired item is there until it's removed.
+func (m *OrderedMap[K, V]) GetAtRevision(key K, revision uint64) (V, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var value V
+	if err := m.checkRevision(revision); err != nil {
+		return value, false, err
+	}
+	node, exists := m.items[key]
+	if exists {
+		value = node.value
+	}
+	// The earliest change after the revision has the state at it
+	for i := len(m.changes) - 1; i >= 0 && m.changes[i].revision > revision; i-- {
+		if c := m.changes[i]; c.key == key {
+			value, exists = c.value, c.existed
+		}
+	}
+	if !exists {
+		var zero V
+		return zero, false, nil
+	}
+	return value, true, nil
+}
+
+// GetAllAtRevision returns the items in the order they had at the revision. The expiry
+// isn't checked, the expired items are there until they are removed.
+func (m *OrderedMap[K, V]) GetAllAtRevision(revision uint64) (Entries[K, V], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.checkRevision(revision); err != nil {
+		return nil, err
+	}
+	v := m.pastView(revision)
+	items, _ := v.collect(v.head, 0, false)
+	return items, nil
+}
+
+// PageAtRevision returns the page of the items as they were at the revision, see Page.
+// The expiry isn't checked. Only the changes after the revision are undone, but the
+// start of the page is found by walking the items, so prefer PageAfterAtRevision
+// to continue with the next page.
+func (m *OrderedMap[K, V]) PageAtRevision(revision uint64, offset, limit int, reverse bool) (page Entries[K, V], more bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.checkRevision(revision); err != nil {
+		return nil, false, err
+	}
+	v := m.pastView(revision)
+	at := v.first(reverse)
+	for ; at.ok && offset > 0; offset-- {
+		at = v.step(at.key, reverse)
+	}
+	page, more = v.collect(at, limit, reverse)
+	return page, more, nil
+}
+
+// PageAfterAtRevision returns the page of the items following the cursor key as they were
+// at the revision, see PageAfter. It takes the time of the page plus the number of the
+// changes after the revision.
+func (m *OrderedMap[K, V]) PageAfterAtRevision(revision uint64, cursor K, limit int, reverse bool) (page Entries[K, V], more bool, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.checkRevision(revision); err != nil {
+		return nil, false, err
+	}
+	v := m.pastView(revision)
+	if _, exists := v.value(cursor); !exists {
+		return nil, false, ErrCursorNotFound
+	}
+	page, more = v.collect(v.step(cursor, reverse), limit, reverse)
+	return page, more, nil
+}
+
+func (m *OrderedMap[K, V]) checkRevision(revision uint64) error {
+	switch {
+	case revision > m.revision:
+		return ErrFutureRevision
+	case revision < m.revision-uint64(len(m.changes)):
+		return ErrRevisionCompacted
+	}
+	return nil
+}
+
+// record increments the revision and keeps the state of the item before it's changed,
+// the node is nil if the item is added.
+func (m *OrderedMap[K, V]) record(key K, node *node[K, V]) {
+	m.revision++
+	if m.batch != nil {
+		m.keepUndo(key, node)
+	}
+	if m.retention <= 0 {
+		return
+	}
+
+	c := change[K, V]{revision: m.revision, key: key, atHead: true}
+	if node != nil {
+		c.existed = true
+		c.value = node.value
+		if node.prev != nil {
+			c.prev, c.atHead = node.prev.key, false
+		}
+	}
+	m.changes = append(m.changes, c)
+	if len(m.changes) > m.retention {
+		m.changes = m.changes[len(m.changes)-m.retention:]
+	}
+}
+
+// pastView is the order of the items at a past revision: the live list with the later
+// changes undone. Only the links and the values of the items changed after the revision
+// are kept, the others are read from the live list, so the view is built in the time
+// of the changes. It's used with the read lock held.
+type pastView[K comparable, V any] struct {
+	m *OrderedMap[K, V]
+	// Neighbours which differ from the live ones
+	next, prev map[K]link[K]
+	head, tail link[K]
+	// State of the changed items
+	changed map[K]pastItem[V]
+}
+
+// link refers to a neighbour item, ok is false at the end of the list.
+type link[K comparable] struct {
+	key K
+	ok  bool
+}
+
+type pastItem[V any] struct {
+	value  V
+	exists bool
+}
+
+func (m *OrderedMap[K, V]) pastView(revision uint64) *pastView[K, V] {
+	v := &pastView[K, V]{
+		m:       m,
+		next:    make(map[K]link[K]),
+		prev:    make(map[K]link[K]),
+		changed: make(map[K]pastItem[V]),
+	}
+	if m.head != nil {
+		v.head = link[K]{key: m.head.key, ok: true}
+		v.tail = link[K]{key: m.tail.key, ok: true}
+	}
+	// The item preceding the changed one isn't changed by the change,
+	// so it's in place when the change is undone
+	for i := len(m.changes) - 1; i >= 0 && m.changes[i].revision > revision; i-- {
+		c := m.changes[i]
+		if _, exists := v.value(c.key); exists {
+			v.remove(c.key)
+		}
+		if !c.existed {
+			continue
+		}
+		if c.atHead {
+			v.insertAfter(c.key, link[K]{})
+		} else {
+			v.insertAfter(c.key, link[K]{key: c.prev, ok: true})
+		}
+		v.changed[c.key] = pastItem[V]{value: c.value, exists: true}
+	}
+	return v
+}
+
+// value returns the value of the item at the revision.
+func (v *pastView[K, V]) value(key K) (V, bool) {
+	if item, changed := v.changed[key]; changed {
+		return item.value, item.exists
+	}
+	if node, exists := v.m.items[key]; exists {
+		return node.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (v *pastView[K, V]) nextOf(key K) link[K] {
+	if l, changed := v.next[key]; changed {
+		return l
+	}
+	if next := v.m.items[key].next; next != nil {
+		return link[K]{key: next.key, ok: true}
+	}
+	return link[K]{}
+}
+
+func (v *pastView[K, V]) prevOf(key K) link[K] {
+	if l, changed := v.prev[key]; changed {
+		return l
+	}
+	if prev := v.m.items[key].prev; prev != nil {
+		return link[K]{key: prev.key, ok: true}
+	}
+	return link[K]{}
+}
+
+// setNext links the item, the head if it's the end of the list, to the next one.
+func (v *pastView[K, V]) setNext(item, next link[K]) {
+	if item.ok {
+		v.next[item.key] = next
+	} else {
+		v.head = next
+	}
+}
+
+// setPrev links the item, the tail if it's the end of the list, to the previous one.
+func (v *pastView[K, V]) setPrev(item, prev link[K]) {
+	if item.ok {
+		v.prev[item.key] = prev
+	} else {
+		v.tail = prev
+	}
+}
+
+func (v *pastView[K, V]) remove(key K) {
+	prev, next := v.prevOf(key), v.nextOf(key)
+	v.setNext(prev, next)
+	v.setPrev(next, prev)
+	v.changed[key] = pastItem[V]{}
+}
+
+// insertAfter links the item after the previous one, at the head if it's the end of the list.
+func (v *pastView[K, V]) insertAfter(key K, prev link[K]) {
+	item := link[K]{key: key, ok: true}
+	next := v.head
+	if prev.ok {
+		next = v.nextOf(prev.key)
+	}
+	v.setNext(prev, item)
+	v.setPrev(item, prev)
+	v.setNext(item, next)
+	v.setPrev(next, item)
+}
+
+func (v *pastView[K, V]) first(reverse bool) link[K] {
+	if reverse {
+		return v.tail
+	}
+	return v.head
+}
+
+func (v *pastView[K, V]) step(key K, reverse bool) link[K] {
+	if reverse {
+		return v.prevOf(key)
+	}
+	return v.nextOf(key)
+}
+
+// collect copies up to limit items starting from the item, zero limit means no limit.
+func (v *pastView[K, V]) collect(at link[K], limit int, reverse bool) (Entries[K, V], bool) {
+	page := make(Entries[K, V], 0, pageCapacity(limit, len(v.m.items)))
+	for ; at.ok; at = v.step(at.key, reverse) {
+		if limit > 0 && len(page) == limit {
+			return page, true
+		}
+		value, _ := v.value(at.key)
+		page = append(page, Entry[K, V]{Key: at.key, Value: value})
+	}
+	return page, false
+}
@@ -0,0 +1,152 @@
+package orderedmap
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderedMap_Revision(t *testing.T) {
+	m := NewOrderedMap()
+	m.Add("a", "1")
+	m.Add("b", "2")
+	m.Add("c", "3")
+	assert.Equal(t, uint64(3), m.Revision())
+
+	m.Add("a", "4")
+	m.Remove("b")
+	m.MoveToFront("c")
+	// Moving the item to its own position isn't a change
+	m.MoveToFront("c")
+	assert.Equal(t, uint64(6), m.Revision())
+
+	items, err := m.GetAllAtRevision(3)
+	require.NoError(t, err)
+	assert.Equal(t, pairs("a", "1", "b", "2", "c", "3"), items)
+	items, err = m.GetAllAtRevision(5)
+	require.NoError(t, err)
+	assert.Equal(t, pairs("a", "4", "c", "3"), items)
+	items, err = m.GetAllAtRevision(6)
+	require.NoError(t, err)
+	assert.Equal(t, m.GetAll(), items)
+	items, err = m.GetAllAtRevision(0)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	value, ok, err := m.GetAtRevision("a", 3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+	value, ok, err = m.GetAtRevision("b", 4)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "2", value)
+	_, ok, err = m.GetAtRevision("b", 5)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = m.GetAllAtRevision(7)
+	assert.ErrorIs(t, err, ErrFutureRevision)
+}
+
+func TestOrderedMap_RevisionRetention(t *testing.T) {
+	m := NewOrderedMap(WithRetention(2))
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Add(key, key+"1")
+	}
+	assert.Equal(t, uint64(2), m.OldestRevision())
+
+	items, err := m.GetAllAtRevision(2)
+	require.NoError(t, err)
+	assert.Equal(t, pairs("a", "a1", "b", "b1"), items)
+	_, _, err = m.GetAtRevision("a", 1)
+	assert.ErrorIs(t, err, ErrRevisionCompacted)
+
+	m.RestoreRevision(10)
+	assert.Equal(t, uint64(10), m.Revision())
+	assert.Equal(t, uint64(10), m.OldestRevision())
+	m.Add("e", "e1")
+	assert.Equal(t, uint64(11), m.Revision())
+}
+
+// TestOrderedMap_RevisionRandom applies random operations and checks the items
+// read at every kept revision against the ones read when it was the current one.
+func TestOrderedMap_RevisionRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	m := NewOrderedMap(WithCapacity(30, LRU), WithRetention(5000))
+
+	states := map[uint64]Entries[string, string]{0: m.GetAll()}
+	for i := 0; i < 2000; i++ {
+		key := strconv.Itoa(rnd.Intn(40))
+		mark := strconv.Itoa(rnd.Intn(40))
+		value := strconv.Itoa(i)
+		switch rnd.Intn(8) {
+		case 0, 1:
+			m.Add(key, value)
+		case 2:
+			m.Remove(key)
+		case 3:
+			m.Get(key)
+		case 4:
+			m.InsertBefore(mark, key, value)
+		case 5:
+			m.InsertAfter(mark, key, value)
+		case 6:
+			m.MoveToFront(key)
+		case 7:
+			m.MoveToBack(key)
+		}
+		states[m.Revision()] = m.GetAll()
+	}
+
+	for revision, expected := range states {
+		items, err := m.GetAllAtRevision(revision)
+		require.NoError(t, err)
+		assert.Equal(t, expected, items, "revision %d", revision)
+		for i, item := range expected {
+			value, ok, err := m.GetAtRevision(item.Key, revision)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, item.Value, value)
+			if revision%20 != 0 {
+				continue
+			}
+
+			// The pages read at the revision continue the same way in both directions
+			page, more, err := m.PageAfterAtRevision(revision, item.Key, 3, false)
+			require.NoError(t, err)
+			end := i + 4
+			if end > len(expected) {
+				end = len(expected)
+			}
+			assert.Equal(t, expected[i+1:end], page, "revision %d after %s", revision, item.Key)
+			assert.Equal(t, end < len(expected), more)
+			page, _, err = m.PageAfterAtRevision(revision, item.Key, 0, true)
+			require.NoError(t, err)
+			assert.Equal(t, reversed(expected[:i]), page, "revision %d before %s", revision, item.Key)
+		}
+		page, more, err := m.PageAtRevision(revision, 2, 3, false)
+		require.NoError(t, err)
+		if len(expected) > 2 {
+			to := 5
+			if to > len(expected) {
+				to = len(expected)
+			}
+			assert.Equal(t, expected[2:to], page, "revision %d from 2", revision)
+			assert.Equal(t, to < len(expected), more)
+		} else {
+			assert.Empty(t, page)
+		}
+	}
+}
+
+func reversed(items Entries[string, string]) Entries[string, string] {
+	result := make(Entries[string, string], 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		result = append(result, items[i])
+	}
+	return result
+}
@@ -0,0 +1,210 @@
+package orderedmap
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultHistorySize is the number of the latest changes kept to resume the subscriptions.
+const DefaultHistorySize = 1024
+
+// subscriptionBuffer is the number of the changes queued for a subscriber.
+// The subscriber which falls behind more is cancelled.
+const subscriptionBuffer = 256
+
+var (
+	// ErrHistoryCompacted is returned when the changes to resume from are no longer kept.
+	ErrHistoryCompacted = errors.New("the changes to resume from are no longer kept")
+	// ErrSubscriberTooSlow is the reason of the subscription cancelled because it fell behind the changes.
+	ErrSubscriberTooSlow = errors.New("subscriber fell behind the changes")
+	// ErrUnknownSeq is returned when the sequence number to resume from wasn't assigned yet.
+	ErrUnknownSeq = errors.New("the sequence number to resume from is ahead of the map")
+)
+
+// EventType is the kind of the change.
+type EventType int
+
+const (
+	// Added is a new key appended to the tail
+	Added EventType = iota + 1
+	// Updated is a new value of the existing key, its position isn't changed
+	Updated
+	// Removed is a removed key with its last value
+	Removed
+	// Expired is a key removed because its TTL has passed, with its last value
+	Expired
+	// Evicted is a key removed to make room for a new one in the full map, with its last value
+	Evicted
+	// Moved is a key moved to another position with its value
+	Moved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Updated:
+		return "updated"
+	case Removed:
+		return "removed"
+	case Expired:
+		return "expired"
+	case Evicted:
+		return "evicted"
+	case Moved:
+		return "moved"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event is a change of the map. The change is numbered by the revision of the map it made,
+// see revision.go, so the numbers continue after the map is restored at its revision.
+// The LRU accesses change the revision without an event, the numbers may have gaps.
+type Event[K comparable, V any] struct {
+	Seq   uint64
+	Type  EventType
+	Key   K
+	Value V
+}
+
+// WithHistory sets the number of the latest changes kept to resume the subscriptions.
+func WithHistory(size int) Option {
+	return func(c *config) {
+		c.historySize = size
+	}
+}
+
+// KeyPrefix returns the filter of the string keys starting with the prefix.
+func KeyPrefix(prefix string) func(string) bool {
+	return func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}
+}
+
+// Subscription receives the changes of the map in the order they are applied.
+type Subscription[K comparable, V any] struct {
+	m      *OrderedMap[K, V]
+	filter func(K) bool
+	events chan Event[K, V]
+	err    error
+}
+
+// Events returns the channel of the changes. It's closed when the subscription
+// is cancelled, Err returns the reason then.
+func (s *Subscription[K, V]) Events() <-chan Event[K, V] {
+	return s.events
+}
+
+// Err returns why the subscription was cancelled by the map, or nil if it was closed by the subscriber.
+// It must be called after the events channel is closed.
+func (s *Subscription[K, V]) Err() error {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return s.err
+}
+
+// Close cancels the subscription.
+func (s *Subscription[K, V]) Close() {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	s.m.unsubscribe(s, nil)
+}
+
+// Seq returns the sequence number of the latest change, the revision of the map.
+// The changes of the open batch aren't counted until it's committed.
+func (m *OrderedMap[K, V]) Seq() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.committedRevision()
+}
+
+// Subscribe returns the subscription to the changes after the sequence number
+// of the keys passing the filter, all the keys if it's nil. The kept changes
+// after the sequence number are delivered first, so the subscriber which lost
+// the connection can resume after the latest change it has seen. Use Seq to
+// subscribe to the new changes only.
+func (m *OrderedMap[K, V]) Subscribe(after uint64, filter func(K) bool) (*Subscription[K, V], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if after > m.committedRevision() {
+		return nil, ErrUnknownSeq
+	}
+	if after < m.compacted {
+		return nil, ErrHistoryCompacted
+	}
+
+	s := &Subscription[K, V]{m: m, filter: filter}
+	var replay []Event[K, V]
+	missed := sort.Search(len(m.history), func(i int) bool { return m.history[i].Seq > after })
+	for _, event := range m.history[missed:] {
+		if s.matches(event.Key) {
+			replay = append(replay, event)
+		}
+	}
+
+	s.events = make(chan Event[K, V], len(replay)+subscriptionBuffer)
+	for _, event := range replay {
+		s.events <- event
+	}
+	m.subscribers[s] = struct{}{}
+
+	return s, nil
+}
+
+func (s *Subscription[K, V]) matches(key K) bool {
+	return s.filter == nil || s.filter(key)
+}
+
+// publish numbers the change by the revision it made and delivers it, or holds it back
+// until the open batch is committed. It's called with the write lock held after the
+// change is recorded.
+func (m *OrderedMap[K, V]) publish(eventType EventType, key K, value V) {
+	event := Event[K, V]{Seq: m.revision, Type: eventType, Key: key, Value: value}
+	if m.batch != nil {
+		m.batch.events = append(m.batch.events, event)
+		return
+	}
+	m.deliver(event)
+}
+
+// deliver keeps the event in the history and passes it to the subscribers.
+func (m *OrderedMap[K, V]) deliver(event Event[K, V]) {
+	if m.historySize > 0 {
+		m.history = append(m.history, event)
+		if len(m.history) > m.historySize {
+			dropped := len(m.history) - m.historySize
+			m.compacted = m.history[dropped-1].Seq
+			m.history = m.history[dropped:]
+		}
+	} else {
+		m.compacted = event.Seq
+	}
+
+	for s := range m.subscribers {
+		if !s.matches(event.Key) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			// Don't block the writer, the subscriber has to resume
+			m.unsubscribe(s, ErrSubscriberTooSlow)
+		}
+	}
+}
+
+// unsubscribe cancels the subscription with the reason. It's called with the write lock held.
+func (m *OrderedMap[K, V]) unsubscribe(s *Subscription[K, V], err error) {
+	if _, ok := m.subscribers[s]; !ok {
+		return
+	}
+	delete(m.subscribers, s)
+	s.err = err
+	close(s.events)
+}
@@ -0,0 +1,140 @@
+package orderedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receive reads the available events of the subscription.
+func receive(s *Subscription[string, string]) []Event[string, string] {
+	var events []Event[string, string]
+	for {
+		select {
+		case event, ok := <-s.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestOrderedMap_Subscribe(t *testing.T) {
+	m := NewOrderedMap()
+	m.Add("a", "1")
+
+	// Subscribe to the new changes only.
+	s, err := m.Subscribe(m.Seq(), nil)
+	require.NoError(t, err)
+	defer s.Close()
+
+	m.Add("b", "2")
+	m.Add("a", "3")
+	m.Remove("b")
+	m.Remove("c")
+
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 2, Type: Added, Key: "b", Value: "2"},
+		{Seq: 3, Type: Updated, Key: "a", Value: "3"},
+		{Seq: 4, Type: Removed, Key: "b", Value: "2"},
+	}, receive(s))
+}
+
+func TestOrderedMap_SubscribeFilter(t *testing.T) {
+	m := NewOrderedMap()
+
+	s, err := m.Subscribe(0, KeyPrefix("user/"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	m.Add("user/1", "a")
+	m.Add("session/1", "b")
+	m.Add("user/2", "c")
+
+	// The sequence numbers of the map changes are kept.
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 1, Type: Added, Key: "user/1", Value: "a"},
+		{Seq: 3, Type: Added, Key: "user/2", Value: "c"},
+	}, receive(s))
+}
+
+func TestOrderedMap_SubscribeResume(t *testing.T) {
+	m := NewOrderedMap(WithHistory(3))
+	m.Add("a", "1")
+	m.Add("b", "2")
+	m.Add("c", "3")
+	m.Add("d", "4")
+
+	// Resume after the change 2 from the kept history.
+	s, err := m.Subscribe(2, nil)
+	require.NoError(t, err)
+	m.Add("e", "5")
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 3, Type: Added, Key: "c", Value: "3"},
+		{Seq: 4, Type: Added, Key: "d", Value: "4"},
+		{Seq: 5, Type: Added, Key: "e", Value: "5"},
+	}, receive(s))
+	s.Close()
+
+	// The change 2 is no longer kept.
+	_, err = m.Subscribe(1, nil)
+	assert.ErrorIs(t, err, ErrHistoryCompacted)
+
+	_, err = m.Subscribe(6, nil)
+	assert.ErrorIs(t, err, ErrUnknownSeq)
+}
+
+func TestOrderedMap_SubscribeRestored(t *testing.T) {
+	// The items are restored as they are from a snapshot taken at the revision 10
+	m := NewOrderedMap()
+	m.Add("a", "1")
+	m.Add("b", "2")
+	m.RestoreRevision(10)
+	assert.Equal(t, uint64(10), m.Seq())
+
+	// The changes before the snapshot aren't known, the restored items aren't changes
+	_, err := m.Subscribe(1, nil)
+	assert.ErrorIs(t, err, ErrHistoryCompacted)
+
+	s, err := m.Subscribe(10, nil)
+	require.NoError(t, err)
+	m.Add("c", "3")
+	m.InsertBefore("a", "c", "4")
+	assert.Equal(t, []Event[string, string]{
+		{Seq: 11, Type: Added, Key: "c", Value: "3"},
+		{Seq: 12, Type: Updated, Key: "c", Value: "4"},
+		{Seq: 13, Type: Moved, Key: "c", Value: "4"},
+	}, receive(s))
+	s.Close()
+}
+
+func TestOrderedMap_SubscribeTooSlow(t *testing.T) {
+	m := NewOrderedMap()
+
+	s, err := m.Subscribe(0, nil)
+	require.NoError(t, err)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		m.Add("a", "1")
+	}
+
+	events := receive(s)
+	assert.Len(t, events, subscriptionBuffer)
+	_, ok := <-s.Events()
+	assert.False(t, ok)
+	assert.ErrorIs(t, s.Err(), ErrSubscriberTooSlow)
+
+	// The subscriber resumes after the latest change it has seen.
+	s, err = m.Subscribe(events[len(events)-1].Seq, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Event[string, string]{{Seq: subscriptionBuffer + 1, Type: Updated, Key: "a", Value: "1"}}, receive(s))
+
+	s.Close()
+	_, ok = <-s.Events()
+	assert.False(t, ok)
+	assert.NoError(t, s.Err())
+}
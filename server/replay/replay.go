@@ -0,0 +1,218 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+	requestmanager "server/request-manager"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// Operation is a recorded request with its position in the processing order.
+type Operation struct {
+	Seq uint64
+	// Revision is the map revision after the logged operation, 0 for the test data
+	Revision uint64
+	Request  types.Request
+}
+
+// position returns the map revision of the logged operation. Unlike the sequence number
+// it keeps growing across the server restarts with the recovered map. The test data
+// operations are positioned by their sequence numbers.
+func (op Operation) position() uint64 {
+	if op.Revision != 0 {
+		return op.Revision
+	}
+	return op.Seq
+}
+
+// Open opens the recorded file, the gzip compressed files (rotated logs) are decompressed.
+func Open(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return file, nil
+	}
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %v", name, err)
+	}
+	return &gzipFile{Reader: zr, file: file}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (f *gzipFile) Close() error {
+	f.Reader.Close()
+	return f.file.Close()
+}
+
+// appliedResults are the logged results of the operations which changed the map or,
+// like the reads with LRU, its order. The other operations, e.g. the duplicated, failed
+// or rejected ones and the batch entries which operations are logged separately, are skipped.
+var appliedResults = map[string]bool{
+	"added":                      true,
+	"updated":                    true,
+	"inserted":                   true,
+	"removed":                    true,
+	"moved":                      true,
+	"found":                      true,
+	requestmanager.ResultExpired: true,
+	requestmanager.ResultEvicted: true,
+}
+
+// ReadLog reads the operations from the server log written with the JSON format.
+// Only the applied operations are read, see appliedResults.
+// The items are added without TTL and the logged expiries and evictions are replayed as
+// removals, so the items disappear at the same position in the order as they did on the server.
+func ReadLog(r io.Reader) ([]Operation, error) {
+	var ops []Operation
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if data[0] != '{' {
+			return nil, fmt.Errorf("line %d is not a JSON log entry, the log must be written with -log-format=json", line)
+		}
+
+		var entry logger.Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode line %d: %v", line, err)
+		}
+		if entry.Action == "" || !appliedResults[entry.Result] {
+			continue
+		}
+
+		req := types.Request{ID: entry.RequestID, Action: entry.Action, Key: entry.Key, Mark: entry.Mark}
+		// The value of the other actions is the result of the operation
+		switch entry.Action {
+		case types.AddItem, types.InsertBefore, types.InsertAfter:
+			req.Value = entry.Value
+		case requestmanager.ExpireAction, requestmanager.EvictAction:
+			req.Action = types.RemoveItem
+		case types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap, types.RemoveIfValue:
+			// The conditions held when the writes were logged, the applied ones are replayed as they changed the map
+			switch entry.Result {
+			case "added", "updated":
+				req.Action = types.AddItem
+				req.Value = entry.Value
+			case "removed":
+				req.Action = types.RemoveItem
+			}
+		}
+		ops = append(ops, Operation{Seq: entry.Seq, Revision: entry.Revision, Request: req})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read log: %v", err)
+	}
+
+	return ops, nil
+}
+
+// ReadTestData reads the operations from the client test data file,
+// they are numbered in the order of the file.
+func ReadTestData(r io.Reader) ([]Operation, error) {
+	var ops []Operation
+
+	decoder := json.NewDecoder(r)
+	for decoder.More() {
+		var action types.TestDataAction
+		if err := decoder.Decode(&action); err != nil {
+			return nil, fmt.Errorf("failed to decode test data: %v", err)
+		}
+		ops = append(ops, Operation{Seq: uint64(len(ops) + 1), Request: action.RequestData})
+	}
+
+	return ops, nil
+}
+
+// Apply processes the operations by ProcessRequests on a new map created with the options
+// and returns it. If until isn't 0 the replay stops after the operations logged at the map
+// revision, including the evictions logged with them, or after the test data operation
+// with the sequence number.
+func Apply(ops []Operation, until uint64, logger *logger.Logger, opts ...orderermap.Option) *orderermap.StringMap {
+	dataStorage := orderermap.NewOrderedMap(opts...)
+
+	reqs := make(chan types.Request)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		requestmanager.ProcessRequests(reqs, logger, requestmanager.WithStorage(dataStorage))
+	}()
+
+	for _, op := range ops {
+		if until != 0 && op.position() > until {
+			break
+		}
+		reqs <- op.Request
+	}
+	close(reqs)
+	<-done
+
+	return dataStorage
+}
+
+// Diff describes how the actual map content differs from the expected one:
+// the missing, unexpected and changed keys, and the first position the order of
+// the common keys differs at. It returns nothing if the contents are equal.
+func Diff(expected, actual orderermap.Entries[string, string]) []string {
+	var diff []string
+
+	expectedValues := make(map[string]string, len(expected))
+	for _, entry := range expected {
+		expectedValues[entry.Key] = entry.Value
+	}
+	actualValues := make(map[string]string, len(actual))
+	for _, entry := range actual {
+		actualValues[entry.Key] = entry.Value
+	}
+
+	var expectedOrder, actualOrder []string
+	for _, entry := range expected {
+		value, ok := actualValues[entry.Key]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("- missing key %q with value %q", entry.Key, entry.Value))
+			continue
+		}
+		if value != entry.Value {
+			diff = append(diff, fmt.Sprintf("~ key %q has value %q, expected %q", entry.Key, value, entry.Value))
+		}
+		expectedOrder = append(expectedOrder, entry.Key)
+	}
+	for _, entry := range actual {
+		if _, ok := expectedValues[entry.Key]; !ok {
+			diff = append(diff, fmt.Sprintf("+ unexpected key %q with value %q", entry.Key, entry.Value))
+			continue
+		}
+		actualOrder = append(actualOrder, entry.Key)
+	}
+
+	for i := range expectedOrder {
+		if expectedOrder[i] != actualOrder[i] {
+			diff = append(diff, fmt.Sprintf("! order differs at position %d of the common keys: expected key %q, got %q", i, expectedOrder[i], actualOrder[i]))
+			break
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,168 @@
+package replay
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+	requestmanager "server/request-manager"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestReplayLog(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "server.log")
+
+	// Record the operations the way the server does
+	myLogger, err := logger.NewLogger(logFile, logger.WithFormat(logger.JSON))
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	original := orderermap.NewOrderedMap()
+	reqs := make(chan types.Request)
+	done := make(chan struct{})
+	go func() {
+		requestmanager.ProcessRequests(reqs, myLogger, requestmanager.WithStorage(original))
+		close(done)
+	}()
+	requests := []types.Request{
+		{ID: "1", Action: types.AddItem, Key: "a", Value: "1"},
+		{ID: "2", Action: types.AddItem, Key: "b", Value: "2"},
+		{ID: "3", Action: types.GetItem, Key: "a"},
+		{ID: "4", Action: types.RemoveItem, Key: "a"},
+		// Redelivery isn't applied again
+		{ID: "4", Action: types.RemoveItem, Key: "a"},
+		{ID: "5", Action: types.AddItem, Key: "a", Value: "3"},
+		{ID: "6", Action: types.GetAll},
+		// The writes rejected for the negative TTL aren't applied
+		{ID: "7", Action: types.AddItem, Key: "c", Value: "4", TTL: -time.Second},
+		{ID: "8", Action: types.InsertBefore, Mark: "b", Key: "d", Value: "5", TTL: -time.Second},
+	}
+	for _, req := range requests {
+		reqs <- req
+	}
+	close(reqs)
+	<-done
+	myLogger.Close()
+
+	file, err := Open(logFile)
+	if err != nil {
+		t.Fatalf("Error opening log: %v", err)
+	}
+	defer file.Close()
+	ops, err := ReadLog(file)
+	if err != nil {
+		t.Fatalf("Error reading log: %v", err)
+	}
+	if len(ops) != 5 {
+		t.Fatalf("Read %d operations, expected 5 applied ones: %+v", len(ops), ops)
+	}
+
+	replayLogger, err := logger.NewLogger(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer replayLogger.Close()
+
+	replayed := Apply(ops, 0, replayLogger)
+	if items, expected := replayed.GetAll(), original.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Replayed items = %v, expected %v", items, expected)
+	}
+
+	// Stop right before the key is removed at revision 3
+	replayed = Apply(ops, 2, replayLogger)
+	expected := orderermap.Entries[string, string]{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	if items := replayed.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Replayed items until 2 = %v, expected %v", items, expected)
+	}
+}
+
+func TestReadLog_ExpiryAndEviction(t *testing.T) {
+	// The sequence numbers start again after the restart, the revisions continue
+	data := `{"level":"info","seq":1,"action":"add","key":"a","value":"1","result":"added","revision":1}
+{"level":"info","seq":2,"action":"add","key":"b","value":"2","result":"added","revision":2}
+{"level":"info","seq":3,"action":"expire","key":"a","value":"1","result":"expired","revision":3}
+{"level":"info","seq":1,"action":"add","key":"c","value":"3","result":"added","revision":5}
+{"level":"info","seq":1,"action":"evict","key":"b","value":"2","result":"evicted","revision":5}
+{"level":"info","seq":2,"action":"insertBefore","key":"d","value":"4","mark":"c","result":"inserted","revision":6}
+`
+	ops, err := ReadLog(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Error reading log: %v", err)
+	}
+	expected := []Operation{
+		{Seq: 1, Revision: 1, Request: types.Request{Action: types.AddItem, Key: "a", Value: "1"}},
+		{Seq: 2, Revision: 2, Request: types.Request{Action: types.AddItem, Key: "b", Value: "2"}},
+		{Seq: 3, Revision: 3, Request: types.Request{Action: types.RemoveItem, Key: "a"}},
+		{Seq: 1, Revision: 5, Request: types.Request{Action: types.AddItem, Key: "c", Value: "3"}},
+		{Seq: 1, Revision: 5, Request: types.Request{Action: types.RemoveItem, Key: "b"}},
+		{Seq: 2, Revision: 6, Request: types.Request{Action: types.InsertBefore, Key: "d", Value: "4", Mark: "c"}},
+	}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Operations = %+v, expected %+v", ops, expected)
+	}
+
+	replayLogger, err := logger.NewLogger(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer replayLogger.Close()
+
+	// The eviction is replayed with the add it was logged with
+	replayed := Apply(ops, 5, replayLogger)
+	expectedItems := orderermap.Entries[string, string]{{Key: "c", Value: "3"}}
+	if items := replayed.GetAll(); !reflect.DeepEqual(items, expectedItems) {
+		t.Errorf("Replayed items until 5 = %v, expected %v", items, expectedItems)
+	}
+}
+
+func TestReadLog_TextFormat(t *testing.T) {
+	_, err := ReadLog(strings.NewReader("#1 [add] Added key a with value 1\n"))
+	if err == nil || !strings.Contains(err.Error(), "-log-format=json") {
+		t.Errorf("ReadLog() error = %v, expected the JSON format error", err)
+	}
+}
+
+func TestReadTestData(t *testing.T) {
+	data := `{"RequestData":{"Action":"add","Key":"a","Value":"1"},"ExpectedResponse":{}}
+{"RequestData":{"Action":"add","Key":"b","Value":"2"},"ExpectedResponse":{}}
+{"RequestData":{"Action":"remove","Key":"a"},"ExpectedResponse":{}}
+`
+	ops, err := ReadTestData(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Error reading test data: %v", err)
+	}
+	expected := []Operation{
+		{Seq: 1, Request: types.Request{Action: types.AddItem, Key: "a", Value: "1"}},
+		{Seq: 2, Request: types.Request{Action: types.AddItem, Key: "b", Value: "2"}},
+		{Seq: 3, Request: types.Request{Action: types.RemoveItem, Key: "a"}},
+	}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Errorf("Operations = %+v, expected %+v", ops, expected)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	expected := orderermap.Entries[string, string]{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}, {Key: "c", Value: "3"}}
+
+	if diff := Diff(expected, expected); len(diff) != 0 {
+		t.Errorf("Diff of equal contents = %v, expected none", diff)
+	}
+
+	actual := orderermap.Entries[string, string]{{Key: "c", Value: "3"}, {Key: "a", Value: "5"}, {Key: "d", Value: "4"}}
+	want := []string{
+		`~ key "a" has value "5", expected "1"`,
+		`- missing key "b" with value "2"`,
+		`+ unexpected key "d" with value "4"`,
+		`! order differs at position 0 of the common keys: expected key "a", got "c"`,
+	}
+	if diff := Diff(expected, actual); !reflect.DeepEqual(diff, want) {
+		t.Errorf("Diff = %q, expected %q", diff, want)
+	}
+}
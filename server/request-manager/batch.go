@@ -0,0 +1,134 @@
+package requestmanager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	logger "server/logger"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// Results of the logged batch requests.
+const (
+	ResultApplied  = "applied"
+	ResultRejected = "rejected"
+)
+
+// batch applies the mutations of the request atomically. The operations are applied
+// to the map one by one, the changes are undone and the batch is rejected as a whole
+// if any of them fails. The applied batch is persisted as a single record, so a crash
+// can't leave a part of it in the map.
+func (p *processor) batch(req types.Request) (types.Response, error) {
+	var resp types.Response
+	if err := checkBatch(req); err != nil {
+		return p.rejectBatch(req, err), nil
+	}
+
+	// The expiry times are set before the batch is persisted
+	ops := make([]types.Request, len(req.Ops))
+	for i, op := range req.Ops {
+		op.ExpiresAt = nil
+		if op.TTL > 0 {
+			expiresAt := p.started.Add(op.TTL)
+			op.ExpiresAt = &expiresAt
+		}
+		ops[i] = op
+	}
+	req.Ops = ops
+
+	// The operations are logged with the sequence number of the batch once it's applied
+	p.dataStorage.Begin()
+	p.inBatch = true
+	resp.Results = make([]types.Response, 0, len(ops))
+	for i, op := range ops {
+		op.ID = req.ID
+		result, err := p.processRequest(op)
+		if err == nil {
+			err = opError(op, result)
+		}
+		if err != nil {
+			p.inBatch = false
+			p.batchLog = nil
+			p.dataStorage.Rollback()
+			return p.rejectBatch(req, fmt.Errorf("operation %d: %v", i, err)), nil
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	p.inBatch = false
+
+	if err := p.persist(req); err != nil {
+		p.batchLog = nil
+		p.dataStorage.Rollback()
+		p.log(req, logger.Entry{
+			Level:   logger.Error,
+			Result:  ResultFailed,
+			Message: fmt.Sprintf("[batch] Failed to apply batch of %d operations: %v", len(req.Ops), err),
+		})
+		return types.Response{}, err
+	}
+	p.dataStorage.Commit()
+	for _, entry := range p.batchLog {
+		p.logger.LogEntry(entry)
+	}
+	p.batchLog = nil
+	p.log(req, logger.Entry{
+		Level:   logger.Info,
+		Result:  ResultApplied,
+		Message: fmt.Sprintf("[batch] Applied batch of %d operations", len(ops)),
+	})
+	return resp, nil
+}
+
+// rejectBatch answers the batch which wasn't applied because of the error.
+func (p *processor) rejectBatch(req types.Request, err error) types.Response {
+	p.log(req, logger.Entry{
+		Level:   logger.Warn,
+		Result:  ResultRejected,
+		Message: fmt.Sprintf("[batch] Rejected batch of %d operations: %v", len(req.Ops), err),
+	})
+	return types.Response{Error: err.Error()}
+}
+
+// isBatchAction reports whether the action is allowed in a batch. Only the unconditional mutations are.
+func isBatchAction(action string) bool {
+	switch action {
+	case types.AddItem, types.RemoveItem, types.InsertBefore, types.InsertAfter, types.MoveToFront, types.MoveToBack:
+		return true
+	}
+	return false
+}
+
+// checkBatch returns the error of the first operation of the batch which can't be applied whatever the map has.
+func checkBatch(req types.Request) error {
+	if len(req.Ops) == 0 {
+		return fmt.Errorf("the batch has no operations")
+	}
+	for i, op := range req.Ops {
+		if !isBatchAction(op.Action) {
+			return fmt.Errorf("operation %d: action %q isn't allowed in a batch", i, op.Action)
+		}
+		if strings.TrimSpace(op.Key) == "" {
+			return fmt.Errorf("operation %d: the key is required", i)
+		}
+	}
+	return nil
+}
+
+// opError returns why the applied operation of the batch failed, nil if it succeeded.
+func opError(op types.Request, result types.Response) error {
+	switch {
+	case result.Error != "":
+		return errors.New(result.Error)
+	case result.Found:
+		return nil
+	}
+	switch op.Action {
+	case types.RemoveItem, types.MoveToFront, types.MoveToBack:
+		return fmt.Errorf("key %s doesn't exist", op.Key)
+	case types.InsertBefore, types.InsertAfter:
+		return fmt.Errorf("mark key %s doesn't exist", op.Mark)
+	}
+	return nil
+}
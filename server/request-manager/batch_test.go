@@ -0,0 +1,130 @@
+package requestmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"server/logger"
+	"strings"
+	"testing"
+	"time"
+
+	orderermap "server/orderer-map"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_Batch(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	writeAheadLog, err := wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	dataStorage := orderermap.NewOrderedMap(orderermap.WithCapacity(3, orderermap.FIFO))
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 10)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder), WithStorage(dataStorage), WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	requests := []struct {
+		req      types.Request
+		expected types.Response
+	}{
+		{types.Request{Action: types.AddItem, Key: "a", Value: "1"}, types.Response{}},
+		{types.Request{Action: types.AddItem, Key: "b", Value: "2"}, types.Response{}},
+		// Inserting x evicts b, so its removal fails and nothing is applied
+		{types.Request{Action: types.Batch, Ops: []types.Request{
+			{Action: types.AddItem, Key: "c", Value: "3"},
+			{Action: types.InsertAfter, Mark: "a", Key: "x", Value: "4"},
+			{Action: types.RemoveItem, Key: "b"},
+		}}, types.Response{Error: "operation 2: key b doesn't exist"}},
+		{types.Request{Action: types.Batch, Ops: []types.Request{
+			{Action: types.AddItem, Key: "c", Value: "3"},
+			{Action: types.InsertAfter, Mark: "a", Key: "x", Value: "4"},
+			{Action: types.MoveToFront, Key: "c"},
+		}}, types.Response{Results: []types.Response{{}, {Found: true}, {Found: true}}}},
+		{types.Request{Action: types.Batch, Ops: []types.Request{{Action: types.GetItem, Key: "a"}}},
+			types.Response{Error: `operation 0: action "get" isn't allowed in a batch`}},
+		{types.Request{Action: types.Batch}, types.Response{Error: "the batch has no operations"}},
+		{types.Request{Action: types.Batch, Ops: []types.Request{
+			{Action: types.RemoveItem, Key: "x"},
+			{Action: types.AddItem, Key: "y", Value: "5", TTL: -time.Second},
+		}}, types.Response{Error: "operation 1: negative TTL -1s"}},
+	}
+	for _, r := range requests {
+		reqs <- r.req
+	}
+	close(reqs)
+	<-done
+	myLogger.Flush()
+	writeAheadLog.Close()
+
+	for i, r := range requests {
+		if resp := <-responder.responses; !reflect.DeepEqual(resp, r.expected) {
+			t.Errorf("Response %d to %s = %+v, expected %+v", i, r.req.Action, resp, r.expected)
+		}
+	}
+
+	expected := orderermap.Entries[string, string]{{Key: "c", Value: "3"}, {Key: "a", Value: "1"}, {Key: "x", Value: "4"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Items = %v, expected %v", items, expected)
+	}
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	for _, line := range []string{
+		"#3 [batch] Rejected batch of 3 operations: operation 2: key b doesn't exist",
+		"#4 [insertAfter] Inserted key x with value 4 after key a",
+		"#4 [evict] Key b evicted by key x",
+		"#4 [batch] Applied batch of 3 operations",
+	} {
+		if !strings.Contains(string(fileContent), line+"\n") {
+			t.Errorf("Expected %q in the log, got %q", line, fileContent)
+		}
+	}
+	// The operations of the rejected batches are undone and aren't logged
+	for _, line := range []string{"#3 [add]", "#3 [evict]", "#7 [remove]"} {
+		if strings.Contains(string(fileContent), line) {
+			t.Errorf("Unexpected %q in the log, got %q", line, fileContent)
+		}
+	}
+
+	// The batch is persisted as a single record and recovered as a whole
+	writeAheadLog, err = wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
+	}
+	defer writeAheadLog.Close()
+	if lastSeq := writeAheadLog.LastSeq(); lastSeq != 3 {
+		t.Errorf("Last record = %d, expected 3", lastSeq)
+	}
+
+	recovered := orderermap.NewOrderedMap(orderermap.WithCapacity(3, orderermap.FIFO))
+	if err := Recover(recovered, nil, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering from write-ahead log: %v", err)
+	}
+	if items := recovered.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Recovered items = %v, expected %v", items, expected)
+	}
+}
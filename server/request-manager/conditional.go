@@ -0,0 +1,124 @@
+package requestmanager
+
+import (
+	"fmt"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// ResultConflict is the logged result of the conditional write which condition didn't hold.
+const ResultConflict = "conflict"
+
+// conditional applies the write if its condition holds. Otherwise Conflict is set
+// and the response holds the current state of the item. Only the applied writes
+// are persisted, so they are recovered without checking the conditions again.
+// They are persisted with the version of the item, see appliedResponse.
+func (p *processor) conditional(req types.Request) (types.Response, error) {
+	var resp types.Response
+	needsExpected := req.Action == types.CompareAndSwap || req.Action == types.RemoveIfValue
+	if needsExpected && req.Expected == "" && req.Version == 0 {
+		resp.Error = fmt.Sprintf("the expected value or version is required for %s", req.Action)
+		p.log(req, logger.Entry{
+			Level:   logger.Warn,
+			Result:  "invalid condition",
+			Message: fmt.Sprintf("[%s] No expected value or version for key %s", req.Action, req.Key),
+		})
+		return resp, nil
+	}
+	if req.Action != types.RemoveIfValue && !p.setExpiresAt(&req, &resp) {
+		return resp, nil
+	}
+
+	value, exists := p.dataStorage.Peek(req.Key)
+	version, _ := p.dataStorage.Version(req.Key)
+	resp.Found = exists
+	if !conditionHolds(req, exists, value, version) {
+		resp.Value = value
+		resp.Version = version
+		resp.Conflict = true
+		message := fmt.Sprintf("[%s] Key %s doesn't exist", req.Action, req.Key)
+		if exists {
+			message = fmt.Sprintf("[%s] Key %s has value %s at version %d", req.Action, req.Key, value, version)
+		}
+		p.log(req, logger.Entry{
+			Level:   logger.Info,
+			Value:   req.Value,
+			Result:  ResultConflict,
+			Message: message,
+		})
+		return resp, nil
+	}
+
+	// The write is applied first to persist the version it gives to the item,
+	// it's undone if it can't be persisted
+	p.dataStorage.Begin()
+	evicted := applyConditional(p.dataStorage, req)
+	req.AppliedVersion = 0
+	if req.Action != types.RemoveIfValue {
+		req.AppliedVersion, _ = p.dataStorage.Version(req.Key)
+	}
+	if err := p.persist(req); err != nil {
+		p.dataStorage.Rollback()
+		p.log(req, logger.Entry{
+			Level:   logger.Error,
+			Value:   req.Value,
+			Result:  ResultFailed,
+			Message: fmt.Sprintf("[%s] Failed to change key %s: %v", req.Action, req.Key, err),
+		})
+		return resp, err
+	}
+	p.dataStorage.Commit()
+
+	if req.Action == types.RemoveIfValue {
+		p.log(req, logger.Entry{
+			Level:   logger.Info,
+			Value:   value,
+			Result:  "removed",
+			Message: fmt.Sprintf("[%s] Removed key %s with value %s", req.Action, req.Key, value),
+		})
+		return resp, nil
+	}
+
+	resp.Version = req.AppliedVersion
+	result, message := "updated", fmt.Sprintf("[%s] Updated key %s with value %s to version %d", req.Action, req.Key, req.Value, resp.Version)
+	if !exists {
+		result, message = "added", fmt.Sprintf("[%s] Added key %s with value %s", req.Action, req.Key, req.Value)
+	}
+	if req.ExpiresAt != nil {
+		message += fmt.Sprintf(" expiring in %s", req.TTL)
+	}
+	p.log(req, logger.Entry{
+		Level:   logger.Info,
+		Value:   req.Value,
+		Result:  result,
+		Message: message,
+	})
+	p.logEvicted(req, evicted)
+	return resp, nil
+}
+
+// conditionHolds reports whether the item in the state is expected by the conditional write.
+func conditionHolds(req types.Request, exists bool, value string, version uint64) bool {
+	switch req.Action {
+	case types.AddIfAbsent:
+		return !exists
+	case types.UpdateIfPresent:
+		return exists
+	}
+	return exists &&
+		(req.Expected == "" || req.Expected == value) &&
+		(req.Version == 0 || req.Version == version)
+}
+
+// applyConditional applies the write which condition was checked, it's used by the
+// processing and the recovery. The applied write is an add or a remove of the item.
+func applyConditional(dataStorage *orderermap.StringMap, req types.Request) orderermap.Entries[string, string] {
+	if req.Action == types.RemoveIfValue {
+		dataStorage.Remove(req.Key)
+		return nil
+	}
+	return dataStorage.Add(req.Key, req.Value, addOptions(req)...)
+}
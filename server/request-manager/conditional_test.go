@@ -0,0 +1,117 @@
+package requestmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"server/logger"
+	"strings"
+	"testing"
+
+	orderermap "server/orderer-map"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_Conditional(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	writeAheadLog, err := wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	dataStorage := orderermap.NewOrderedMap()
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 10)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder), WithStorage(dataStorage), WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	requests := []struct {
+		req      types.Request
+		expected types.Response
+	}{
+		{types.Request{Action: types.AddIfAbsent, Key: "a", Value: "1"}, types.Response{Version: 1}},
+		{types.Request{Action: types.AddIfAbsent, Key: "a", Value: "2"}, types.Response{Found: true, Value: "1", Version: 1, Conflict: true}},
+		{types.Request{Action: types.UpdateIfPresent, Key: "b", Value: "1"}, types.Response{Conflict: true}},
+		{types.Request{Action: types.UpdateIfPresent, Key: "a", Value: "3"}, types.Response{Found: true, Version: 2}},
+		{types.Request{Action: types.CompareAndSwap, Key: "a", Value: "4", Expected: "1"}, types.Response{Found: true, Value: "3", Version: 2, Conflict: true}},
+		{types.Request{Action: types.CompareAndSwap, Key: "a", Value: "4", Version: 2}, types.Response{Found: true, Version: 3}},
+		{types.Request{Action: types.CompareAndSwap, Key: "a", Value: "5"}, types.Response{Error: "the expected value or version is required for compareAndSwap"}},
+		{types.Request{Action: types.RemoveIfValue, Key: "a", Expected: "3"}, types.Response{Found: true, Value: "4", Version: 3, Conflict: true}},
+		// The version of the new key is the revision it's added at
+		{types.Request{Action: types.AddIfAbsent, Key: "b", Value: "5"}, types.Response{Version: 4}},
+		{types.Request{Action: types.RemoveIfValue, Key: "b", Expected: "5"}, types.Response{Found: true}},
+	}
+	for _, r := range requests {
+		reqs <- r.req
+	}
+	close(reqs)
+	<-done
+	myLogger.Flush()
+	writeAheadLog.Close()
+
+	for i, r := range requests {
+		if resp := <-responder.responses; !reflect.DeepEqual(resp, r.expected) {
+			t.Errorf("Response %d to %s = %+v, expected %+v", i, r.req.Action, resp, r.expected)
+		}
+	}
+
+	expected := orderermap.Entries[string, string]{{Key: "a", Value: "4"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Items = %v, expected %v", items, expected)
+	}
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	for _, line := range []string{
+		"#2 [addIfAbsent] Key a has value 1 at version 1",
+		"#3 [updateIfPresent] Key b doesn't exist",
+		"#6 [compareAndSwap] Updated key a with value 4 to version 3",
+		"#10 [removeIfValue] Removed key b with value 5",
+	} {
+		if !strings.Contains(string(fileContent), line+"\n") {
+			t.Errorf("Expected %q in the log, got %q", line, fileContent)
+		}
+	}
+
+	// Only the applied writes are persisted and the versions are recovered from them
+	writeAheadLog, err = wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
+	}
+	defer writeAheadLog.Close()
+	if lastSeq := writeAheadLog.LastSeq(); lastSeq != 5 {
+		t.Errorf("Last record = %d, expected 5", lastSeq)
+	}
+
+	recovered := orderermap.NewOrderedMap()
+	if err := Recover(recovered, nil, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering from write-ahead log: %v", err)
+	}
+	if items := recovered.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Recovered items = %v, expected %v", items, expected)
+	}
+	if version, _ := recovered.Version("a"); version != 3 {
+		t.Errorf("Recovered version = %d, expected 3", version)
+	}
+}
@@ -0,0 +1,109 @@
+package requestmanager
+
+import (
+	"log"
+
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// DefaultDedupWindow is the number of the latest request IDs
+// remembered to detect the duplicated deliveries.
+const DefaultDedupWindow = 10000
+
+// WithDedupWindow sets the number of the latest request IDs remembered
+// to detect the duplicated deliveries, 0 disables the detection.
+func WithDedupWindow(size int) Option {
+	return func(o *options) {
+		o.dedupWindow = size
+	}
+}
+
+// dedupWindow keeps the responses of the latest processed requests by their IDs.
+// The oldest request is forgotten when the window is full.
+type dedupWindow struct {
+	size      int
+	responses map[string]types.Response
+	// Ring buffer of the IDs in the processing order
+	ids  []string
+	next int
+}
+
+func newDedupWindow(size int) *dedupWindow {
+	return &dedupWindow{
+		size:      size,
+		responses: make(map[string]types.Response, size),
+		ids:       make([]string, size),
+	}
+}
+
+func (w *dedupWindow) Get(id string) (types.Response, bool) {
+	if id == "" || w.size == 0 {
+		return types.Response{}, false
+	}
+	resp, ok := w.responses[id]
+	return resp, ok
+}
+
+func (w *dedupWindow) Add(id string, resp types.Response) {
+	if id == "" || w.size == 0 {
+		return
+	}
+	if _, ok := w.responses[id]; ok {
+		return
+	}
+
+	if oldest := w.ids[w.next]; oldest != "" {
+		delete(w.responses, oldest)
+	}
+	w.ids[w.next] = id
+	w.next = (w.next + 1) % w.size
+	w.responses[id] = resp
+}
+
+// seedDedupWindow remembers the IDs of the latest persisted mutations,
+// so the requests redelivered after a restart aren't applied twice.
+func (p *processor) seedDedupWindow() {
+	if p.wal == nil || p.dedup.size == 0 {
+		return
+	}
+
+	var after uint64
+	if lastSeq := p.wal.LastSeq(); lastSeq > uint64(p.dedup.size) {
+		after = lastSeq - uint64(p.dedup.size)
+	}
+	if firstSeq := p.wal.FirstSeq(); after+1 < firstSeq {
+		after = firstSeq - 1
+	}
+
+	err := p.wal.Replay(after, func(rec wal.Record) error {
+		// Only the applied mutations are persisted
+		resp := appliedResponse(rec.Request)
+		if rec.Request.Action == types.Batch {
+			resp.Results = make([]types.Response, 0, len(rec.Request.Ops))
+			for _, op := range rec.Request.Ops {
+				resp.Results = append(resp.Results, appliedResponse(op))
+			}
+		}
+		p.dedup.Add(rec.Request.ID, resp)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to restore the processed request IDs: %v", err)
+	}
+}
+
+// appliedResponse returns the response to the persisted mutation.
+func appliedResponse(req types.Request) types.Response {
+	switch req.Action {
+	case types.RemoveItem, types.InsertBefore, types.InsertAfter, types.MoveToFront, types.MoveToBack,
+		types.RemoveIfValue:
+		return types.Response{Found: true}
+	case types.UpdateIfPresent, types.CompareAndSwap:
+		return types.Response{Found: true, Version: req.AppliedVersion}
+	case types.AddIfAbsent:
+		return types.Response{Version: req.AppliedVersion}
+	}
+	return types.Response{}
+}
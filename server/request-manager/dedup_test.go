@@ -0,0 +1,148 @@
+package requestmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"server/logger"
+	"strings"
+	"testing"
+
+	orderermap "server/orderer-map"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestDedupWindow(t *testing.T) {
+	w := newDedupWindow(2)
+
+	w.Add("1", types.Response{Value: "one"})
+	w.Add("2", types.Response{Value: "two"})
+
+	if resp, ok := w.Get("1"); !ok || resp.Value != "one" {
+		t.Errorf("w.Get(\"1\") = (%v, %v), expected (%v, %v)", resp, ok, "one", true)
+	}
+
+	// The oldest ID is forgotten
+	w.Add("3", types.Response{Value: "three"})
+	if _, ok := w.Get("1"); ok {
+		t.Errorf("Expected the oldest request ID to be forgotten")
+	}
+	if _, ok := w.Get("3"); !ok {
+		t.Errorf("Expected the latest request ID to be remembered")
+	}
+
+	// Requests without ID are never remembered
+	w.Add("", types.Response{})
+	if _, ok := w.Get(""); ok {
+		t.Errorf("Expected the empty request ID to be ignored")
+	}
+}
+
+func TestProcessRequests_Duplicates(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	writeAheadLog, err := wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 10)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder), WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	add := types.Request{ID: "1", Action: types.AddItem, Key: "a", Value: "1"}
+	reqs <- add
+	reqs <- types.Request{ID: "2", Action: types.AddItem, Key: "b", Value: "2"}
+	reqs <- types.Request{ID: "3", Action: types.GetItem, Key: "a"}
+	// The redelivered add must not move the key to the tail
+	reqs <- add
+	// The redelivered get is answered with the original result
+	reqs <- types.Request{ID: "3", Action: types.GetItem, Key: "a"}
+	addIfAbsent := types.Request{ID: "4", Action: types.AddIfAbsent, Key: "c", Value: "3"}
+	reqs <- addIfAbsent
+	compareAndSwap := types.Request{ID: "5", Action: types.CompareAndSwap, Key: "c", Value: "4", Expected: "3"}
+	reqs <- compareAndSwap
+	close(reqs)
+	<-done
+	writeAheadLog.Close()
+
+	if lastSeq := writeAheadLog.LastSeq(); lastSeq != 4 {
+		t.Errorf("Expected 4 records in the log, got %d", lastSeq)
+	}
+	for i := 0; i < 4; i++ {
+		<-responder.responses
+	}
+	if resp := <-responder.responses; !resp.Found || resp.Value != "1" {
+		t.Errorf("Duplicate get response = %+v, expected the original one", resp)
+	}
+	conditionalResponses := []types.Response{<-responder.responses, <-responder.responses}
+
+	// Restart and redeliver the add request again
+	writeAheadLog, err = wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
+	}
+	defer writeAheadLog.Close()
+
+	dataStorage := orderermap.NewOrderedMap()
+	if err := Recover(dataStorage, nil, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering: %v", err)
+	}
+
+	reqs = make(chan types.Request)
+	done = make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder), WithStorage(dataStorage), WithWAL(writeAheadLog))
+		close(done)
+	}()
+	reqs <- add
+	// The redelivered conditional writes are answered with the versions they gave
+	reqs <- addIfAbsent
+	reqs <- compareAndSwap
+	close(reqs)
+	<-done
+	// Wait for the queued log writes
+	myLogger.Flush()
+
+	<-responder.responses
+	for i, expected := range conditionalResponses {
+		if resp := <-responder.responses; !reflect.DeepEqual(resp, expected) {
+			t.Errorf("Duplicate conditional response %d = %+v, expected the original %+v", i, resp, expected)
+		}
+	}
+	if conditionalResponses[1].Version == 0 {
+		t.Errorf("Expected the version of the swapped item, got %+v", conditionalResponses[1])
+	}
+
+	expected := orderermap.Entries[string, string]{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}, {Key: "c", Value: "4"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Items = %v, expected %v", items, expected)
+	}
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	if count := strings.Count(string(fileContent), "[add] Duplicate request 1 for key a"); count != 2 {
+		t.Errorf("Expected 2 duplicate add messages in the log, got %d in %q", count, fileContent)
+	}
+}
@@ -0,0 +1,48 @@
+package requestmanager
+
+import (
+	"fmt"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// TouchAction is the action of the write-ahead log records of the items accessed
+// with the LRU eviction policy. The access moves the item to the tail, so it's
+// persisted for the order to be recovered. It isn't accepted from the clients.
+const TouchAction = "touch"
+
+// EvictAction is the logged action of the item evicted from the full map.
+// The evictions aren't persisted, replaying the adds evicts the same items.
+const EvictAction = "evict"
+
+// ResultEvicted is the logged result of the evicted item.
+const ResultEvicted = "evicted"
+
+// touch persists the access to the item and moves it to the tail.
+func (p *processor) touch(req types.Request) error {
+	if err := p.persist(types.Request{Action: TouchAction, Key: req.Key}); err != nil {
+		p.log(req, logger.Entry{
+			Level:   logger.Error,
+			Result:  ResultFailed,
+			Message: fmt.Sprintf("[%s] Failed to touch key %s: %v", req.Action, req.Key, err),
+		})
+		return err
+	}
+	p.dataStorage.Touch(req.Key)
+	return nil
+}
+
+// logEvicted logs the items evicted by the add request with its sequence number.
+func (p *processor) logEvicted(req types.Request, evicted orderermap.Entries[string, string]) {
+	for _, item := range evicted {
+		p.log(types.Request{ID: req.ID, Action: EvictAction, Key: item.Key}, logger.Entry{
+			Level:   logger.Info,
+			Value:   item.Value,
+			Result:  ResultEvicted,
+			Message: fmt.Sprintf("[evict] Key %s evicted by key %s", item.Key, req.Key),
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package requestmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"server/logger"
+	"strings"
+	"testing"
+
+	orderermap "server/orderer-map"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_Eviction(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name(), logger.WithLevel(logger.Info))
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	writeAheadLog, err := wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	dataStorage := orderermap.NewOrderedMap(orderermap.WithCapacity(2, orderermap.LRU))
+	reqs := make(chan types.Request)
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithStorage(dataStorage), WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	reqs <- types.Request{Action: types.AddItem, Key: "a", Value: "1"}
+	reqs <- types.Request{Action: types.AddItem, Key: "b", Value: "2"}
+	// The access makes b the least recently used
+	reqs <- types.Request{Action: types.GetItem, Key: "a"}
+	reqs <- types.Request{Action: types.AddItem, Key: "c", Value: "3"}
+	close(reqs)
+	<-done
+	myLogger.Flush()
+	writeAheadLog.Close()
+
+	expected := orderermap.Entries[string, string]{{Key: "a", Value: "1"}, {Key: "c", Value: "3"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Items = %v, expected %v", items, expected)
+	}
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	if !strings.Contains(string(fileContent), "#4 [evict] Key b evicted by key c\n") {
+		t.Errorf("Expected the eviction to be logged, got %q", fileContent)
+	}
+	// The access reorders the items, so it's logged above the debug level for the replay
+	if !strings.Contains(string(fileContent), "#3 [get] Got key a with value 1\n") {
+		t.Errorf("Expected the access to be logged, got %q", fileContent)
+	}
+
+	// The access is persisted, so the same key is evicted on recovery
+	writeAheadLog, err = wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
+	}
+	defer writeAheadLog.Close()
+
+	recovered := orderermap.NewOrderedMap(orderermap.WithCapacity(2, orderermap.LRU))
+	if err := Recover(recovered, nil, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering from write-ahead log: %v", err)
+	}
+	if items := recovered.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Recovered items = %v, expected %v", items, expected)
+	}
+}
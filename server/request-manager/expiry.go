@@ -0,0 +1,92 @@
+package requestmanager
+
+import (
+	"fmt"
+	"time"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// ExpireAction is the action of the write-ahead log records of the expired items.
+// It isn't accepted from the clients.
+const ExpireAction = "expire"
+
+// ResultExpired is the logged result of the item removed on its expiry.
+const ResultExpired = "expired"
+
+// expiryTimer returns the channel which fires at the earliest expiry time
+// of the map items, nil if no item expires.
+func (p *processor) expiryTimer() <-chan time.Time {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	next, ok := p.dataStorage.NextExpiry()
+	if !ok {
+		return nil
+	}
+	wait := time.Until(next)
+	if wait <= 0 {
+		// The expiry failed to be persisted, retry it later
+		wait = requeueDelay
+	}
+	p.timer = time.NewTimer(wait)
+	return p.timer.C
+}
+
+// expire removes the expired items in the expiry order. Every removal is
+// persisted and logged as a separate operation, so the items expire in the same
+// order relative to the requests after recovery and in the replayed log.
+func (p *processor) expire() {
+	for _, item := range p.dataStorage.Expired(time.Now()) {
+		p.seq++
+		p.started = time.Now()
+		req := types.Request{Action: ExpireAction, Key: item.Key}
+		if err := p.persist(req); err != nil {
+			// The item stays hidden and the expiry is retried before the next request
+			p.log(req, logger.Entry{
+				Level:   logger.Error,
+				Result:  ResultFailed,
+				Message: fmt.Sprintf("[expire] Failed to expire key %s: %v", item.Key, err),
+			})
+			return
+		}
+		p.dataStorage.Expire(item.Key)
+		p.log(req, logger.Entry{
+			Level:   logger.Info,
+			Value:   item.Value,
+			Result:  ResultExpired,
+			Message: fmt.Sprintf("[expire] Key %s expired", item.Key),
+		})
+	}
+}
+
+// setExpiresAt sets the expiry time of the added item from its TTL, the one sent by
+// the client is ignored. It answers and returns false if the TTL is invalid.
+func (p *processor) setExpiresAt(req *types.Request, resp *types.Response) bool {
+	req.ExpiresAt = nil
+	if req.TTL < 0 {
+		resp.Error = fmt.Sprintf("negative TTL %s", req.TTL)
+		p.log(*req, logger.Entry{
+			Level:   logger.Warn,
+			Result:  "invalid TTL",
+			Message: fmt.Sprintf("[%s] Negative TTL %s for key %s", req.Action, req.TTL, req.Key),
+		})
+		return false
+	}
+	if req.TTL > 0 {
+		expiresAt := p.started.Add(req.TTL)
+		req.ExpiresAt = &expiresAt
+	}
+	return true
+}
+
+// addOptions returns the map options of the add request.
+func addOptions(req types.Request) []orderermap.AddOption {
+	if req.ExpiresAt == nil {
+		return nil
+	}
+	return []orderermap.AddOption{orderermap.ExpireAt(*req.ExpiresAt)}
+}
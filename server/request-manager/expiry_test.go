@@ -0,0 +1,105 @@
+package requestmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"server/logger"
+	"strings"
+	"testing"
+	"time"
+
+	orderermap "server/orderer-map"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_Expiry(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	writeAheadLog, err := wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 10)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder), WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	reqs <- types.Request{Action: types.AddItem, Key: "a", Value: "1", TTL: 50 * time.Millisecond}
+	reqs <- types.Request{Action: types.AddItem, Key: "b", Value: "2"}
+	reqs <- types.Request{Action: types.AddItem, Key: "c", Value: "3", TTL: time.Hour}
+	// The expiry time is set by the server only
+	past := time.Now().Add(-time.Hour)
+	reqs <- types.Request{Action: types.AddItem, Key: "e", Value: "5", ExpiresAt: &past}
+	reqs <- types.Request{Action: types.AddItem, Key: "d", Value: "4", TTL: -time.Second}
+	for i := 0; i < 4; i++ {
+		<-responder.responses
+	}
+	if resp := <-responder.responses; resp.Error == "" {
+		t.Errorf("Expected the negative TTL to be rejected")
+	}
+
+	// The item expires without any following request
+	time.Sleep(100 * time.Millisecond)
+	reqs <- types.Request{Action: types.GetAll}
+	close(reqs)
+	<-done
+	myLogger.Flush()
+
+	expectedItems := types.Items{{Key: "b", Value: "2"}, {Key: "c", Value: "3"}, {Key: "e", Value: "5"}}
+	if resp := <-responder.responses; !reflect.DeepEqual(resp.Items, expectedItems) {
+		t.Errorf("Items = %v, expected %v", resp.Items, expectedItems)
+	}
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(fileContent)), "\n")
+	if len(lines) != 7 || lines[5] != "#6 [expire] Key a expired" {
+		t.Errorf("Expected the expiry to be logged before the getAll request, got %q", lines)
+	}
+	writeAheadLog.Close()
+
+	// The expiry is recovered from the log
+	writeAheadLog, err = wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
+	}
+	defer writeAheadLog.Close()
+
+	if lastSeq := writeAheadLog.LastSeq(); lastSeq != 5 {
+		t.Errorf("Expected 5 records in the log, got %d", lastSeq)
+	}
+
+	dataStorage := orderermap.NewOrderedMap()
+	if err := Recover(dataStorage, nil, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering from write-ahead log: %v", err)
+	}
+	expected := orderermap.Entries[string, string]{{Key: "b", Value: "2"}, {Key: "c", Value: "3"}, {Key: "e", Value: "5"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Recovered items = %v, expected %v", items, expected)
+	}
+	if expiries := dataStorage.Expiries(); len(expiries) != 1 || expiries["c"].IsZero() {
+		t.Errorf("Recovered expiries = %v, expected the one of c", expiries)
+	}
+}
@@ -0,0 +1,155 @@
+package requestmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// Page size limits of the paginated reads. The page is kept small
+// enough to be sent in a single queue message.
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// page answers the paginated read with the items and the cursor of the next page.
+func (p *processor) page(req types.Request) types.Response {
+	var resp types.Response
+	if errMsg := validatePage(req); errMsg != "" {
+		resp.Error = errMsg
+		p.log(req, logger.Entry{
+			Level:   logger.Warn,
+			Result:  "invalid page",
+			Message: fmt.Sprintf("[%s] Invalid page: %s", req.Action, errMsg),
+		})
+		return resp
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	var items orderermap.Entries[string, string]
+	var more bool
+	resp.Revision = p.dataStorage.Revision()
+	switch {
+	case req.Revision != 0:
+		// The items are read at the revision of the first page, so the pages are consistent
+		var err error
+		items, more, err = pageAtRevision(p.dataStorage, req, limit)
+		if errors.Is(err, orderermap.ErrCursorNotFound) {
+			return p.cursorNotFound(req)
+		}
+		if err != nil {
+			return p.revisionError(req, err)
+		}
+		resp.Revision = req.Revision
+	case req.Action == types.GetHead:
+		items, more = p.dataStorage.Page(0, limit, false)
+	case req.Action == types.GetTail:
+		items = p.dataStorage.Tail(limit)
+	default:
+		if req.After != "" {
+			var err error
+			items, more, err = p.dataStorage.PageAfter(req.After, limit, req.Reverse)
+			if err != nil {
+				return p.cursorNotFound(req)
+			}
+		} else {
+			items, more = p.dataStorage.Page(req.Offset, limit, req.Reverse)
+		}
+	}
+
+	resp.Items = make(types.Items, 0, len(items))
+	for _, item := range items {
+		resp.Items = append(resp.Items, types.Item{Key: item.Key, Value: item.Value})
+	}
+	if more {
+		resp.Next = items[len(items)-1].Key
+	}
+	b, _ := json.Marshal(items)
+	p.log(req, logger.Entry{
+		Level:   logger.Debug,
+		Value:   string(b),
+		Result:  fmt.Sprintf("%d items", len(items)),
+		Message: fmt.Sprintf("[%s] Page %s", req.Action, string(b)),
+	})
+	return resp
+}
+
+// cursorNotFound answers the page request which cursor key doesn't exist.
+func (p *processor) cursorNotFound(req types.Request) types.Response {
+	p.log(req, logger.Entry{
+		Level:   logger.Debug,
+		Result:  "cursor not found",
+		Message: fmt.Sprintf("[%s] Cursor key %s doesn't exist", req.Action, req.After),
+	})
+	return types.Response{Error: fmt.Sprintf("cursor key %s doesn't exist", req.After)}
+}
+
+// validatePage returns the description of the invalid page parameters, empty if they are valid.
+func validatePage(req types.Request) string {
+	switch {
+	case req.Offset < 0 || req.Limit < 0:
+		return "offset and limit must not be negative"
+	case req.Action != types.GetPage && (req.Offset != 0 || req.After != "" || req.Reverse):
+		return "offset, after and reverse are allowed for getPage only"
+	case req.Offset != 0 && req.After != "":
+		return "offset and after are exclusive"
+	}
+	return ""
+}
+
+// position answers the item at the position or the position of the key.
+func (p *processor) position(req types.Request) types.Response {
+	var resp types.Response
+	if req.Action == types.IndexOf {
+		resp.Index = p.dataStorage.IndexOf(req.Key)
+		resp.Found = resp.Index >= 0
+		if !resp.Found {
+			resp.Index = 0
+			p.log(req, logger.Entry{
+				Level:   logger.Debug,
+				Result:  "not found",
+				Message: fmt.Sprintf("[indexOf] Key %s doesn't exist", req.Key),
+			})
+			return resp
+		}
+		p.log(req, logger.Entry{
+			Level:   logger.Debug,
+			Result:  "found",
+			Message: fmt.Sprintf("[indexOf] Key %s is at position %d", req.Key, resp.Index),
+		})
+		return resp
+	}
+
+	entry, found := p.dataStorage.At(req.Offset)
+	resp.Found = found
+	if !found {
+		p.log(req, logger.Entry{
+			Level:   logger.Debug,
+			Result:  "not found",
+			Message: fmt.Sprintf("[getAt] Position %d is out of range", req.Offset),
+		})
+		return resp
+	}
+	resp.Key = entry.Key
+	resp.Value = entry.Value
+	p.log(req, logger.Entry{
+		Level:   logger.Debug,
+		Value:   entry.Value,
+		Result:  "found",
+		Message: fmt.Sprintf("[getAt] Got key %s with value %s at position %d", entry.Key, entry.Value, req.Offset),
+	})
+	return resp
+}
@@ -0,0 +1,67 @@
+package requestmanager
+
+import (
+	"os"
+	"reflect"
+	"server/logger"
+	"strconv"
+	"testing"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_Page(t *testing.T) {
+	myLogger, err := logger.NewLogger(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 20)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder))
+		close(done)
+	}()
+
+	for i := 1; i <= 5; i++ {
+		reqs <- types.Request{Action: types.AddItem, Key: "k" + strconv.Itoa(i), Value: strconv.Itoa(i)}
+		<-responder.responses
+	}
+
+	items := func(keys ...int) types.Items {
+		result := types.Items{}
+		for _, i := range keys {
+			result = append(result, types.Item{Key: "k" + strconv.Itoa(i), Value: strconv.Itoa(i)})
+		}
+		return result
+	}
+	requests := []struct {
+		req      types.Request
+		expected types.Response
+	}{
+		{types.Request{Action: types.GetPage, Limit: 2}, types.Response{Items: items(1, 2), Next: "k2", Revision: 5}},
+		{types.Request{Action: types.GetPage, After: "k2", Limit: 2}, types.Response{Items: items(3, 4), Next: "k4", Revision: 5}},
+		{types.Request{Action: types.GetPage, After: "k4", Limit: 2}, types.Response{Items: items(5), Revision: 5}},
+		{types.Request{Action: types.GetPage, Offset: 1, Limit: 3, Reverse: true}, types.Response{Items: items(4, 3, 2), Next: "k2", Revision: 5}},
+		{types.Request{Action: types.GetPage}, types.Response{Items: items(1, 2, 3, 4, 5), Revision: 5}},
+		{types.Request{Action: types.GetHead, Limit: 2}, types.Response{Items: items(1, 2), Next: "k2", Revision: 5}},
+		{types.Request{Action: types.GetTail, Limit: 2}, types.Response{Items: items(4, 5), Revision: 5}},
+		{types.Request{Action: types.GetPage, After: "x"}, types.Response{Error: "cursor key x doesn't exist"}},
+		{types.Request{Action: types.GetPage, After: "k1", Offset: 1}, types.Response{Error: "offset and after are exclusive"}},
+		{types.Request{Action: types.GetTail, Limit: -1}, types.Response{Error: "offset and limit must not be negative"}},
+		{types.Request{Action: types.GetAt, Offset: 3}, types.Response{Found: true, Key: "k4", Value: "4"}},
+		{types.Request{Action: types.GetAt, Offset: 5}, types.Response{}},
+		{types.Request{Action: types.IndexOf, Key: "k2"}, types.Response{Found: true, Index: 1}},
+		{types.Request{Action: types.IndexOf, Key: "x"}, types.Response{}},
+	}
+	for i, r := range requests {
+		reqs <- r.req
+		if resp := <-responder.responses; !reflect.DeepEqual(resp, r.expected) {
+			t.Errorf("Response %d to %+v = %+v, expected %+v", i, r.req, resp, r.expected)
+		}
+	}
+	close(reqs)
+	<-done
+}
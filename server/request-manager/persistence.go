@@ -0,0 +1,236 @@
+package requestmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	orderermap "server/orderer-map"
+	"server/snapshot"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// WithWAL makes ProcessRequests append every mutation to the write-ahead log
+// before applying it to the map.
+func WithWAL(log *wal.Log) Option {
+	return func(o *options) {
+		o.wal = log
+	}
+}
+
+// WithSnapshots makes ProcessRequests save the map snapshot to the store after
+// every `every` mutations and once per interval if the map has changed. Zero
+// disables the trigger. The log segments covered by the saved snapshot are removed.
+// Snapshots require the write-ahead log to be enabled by WithWAL.
+func WithSnapshots(store *snapshot.Store, every uint64, interval time.Duration) Option {
+	return func(o *options) {
+		o.snapshots = store
+		o.snapshotEvery = every
+		o.snapshotInterval = interval
+	}
+}
+
+// settings are the map options the persisted mutations are replayed with.
+type settings struct {
+	MaxItems int
+	Eviction string
+}
+
+// CheckSettings fails if the map has another capacity or eviction policy than the one
+// the mutations were persisted with. The evictions and the LRU order aren't persisted,
+// replaying the mutations repeats them only with the same settings. The settings are
+// saved to the file on the first start.
+func CheckSettings(filename string, dataStorage *orderermap.StringMap) error {
+	current := settings{MaxItems: dataStorage.Capacity(), Eviction: dataStorage.EvictionPolicy().String()}
+
+	data, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return saveSettings(filename, current)
+	case err != nil:
+		return fmt.Errorf("failed to read map settings: %v", err)
+	}
+	var saved settings
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to decode map settings %s: %v", filename, err)
+	}
+	if saved != current {
+		return fmt.Errorf("the map was persisted with %d max items and %s eviction, it can't be recovered with %d and %s",
+			saved.MaxItems, saved.Eviction, current.MaxItems, current.Eviction)
+	}
+	return nil
+}
+
+// saveSettings atomically writes the settings to the file.
+func saveSettings(filename string, s settings) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode map settings: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "settings-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create map settings file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write map settings: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync map settings file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close map settings file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to save map settings: %v", err)
+	}
+
+	dir, err := os.Open(filepath.Dir(filename))
+	if err != nil {
+		return fmt.Errorf("failed to open map settings directory: %v", err)
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync map settings directory: %v", err)
+	}
+	return nil
+}
+
+// Recover restores the map from the latest snapshot, if there is a store, and
+// applies the following mutations from the write-ahead log in the order they were processed.
+// The evictions aren't persisted, so the map must have the capacity and the eviction
+// policy it had when the mutations were processed, see CheckSettings.
+func Recover(dataStorage *orderermap.StringMap, store *snapshot.Store, log *wal.Log) error {
+	var after uint64
+	if store != nil {
+		snap, err := store.Latest()
+		if err != nil {
+			return err
+		}
+		if snap != nil {
+			if log.LastSeq() < snap.Seq {
+				return fmt.Errorf("write-ahead log ends at %d before the snapshot %d", log.LastSeq(), snap.Seq)
+			}
+			for _, item := range snap.Items {
+				var opts []orderermap.AddOption
+				if expiresAt, ok := snap.Expiries[item.Key]; ok {
+					opts = append(opts, orderermap.ExpireAt(expiresAt))
+				}
+				if version, ok := snap.Versions[item.Key]; ok {
+					opts = append(opts, orderermap.AtVersion(version))
+				}
+				dataStorage.Add(item.Key, item.Value, opts...)
+			}
+			// The log records replayed after the snapshot make the same changes as when they were processed
+			dataStorage.RestoreRevision(snap.Revision)
+			after = snap.Seq
+		}
+	}
+
+	return log.Replay(after, func(rec wal.Record) error {
+		if err := applyRecord(dataStorage, rec.Request); err != nil {
+			return fmt.Errorf("failed to apply record %d: %v", rec.Seq, err)
+		}
+		return nil
+	})
+}
+
+// applyRecord applies the persisted mutation to the map.
+func applyRecord(dataStorage *orderermap.StringMap, req types.Request) error {
+	switch req.Action {
+	case types.AddItem:
+		dataStorage.Add(req.Key, req.Value, addOptions(req)...)
+	case types.RemoveItem, ExpireAction:
+		dataStorage.Remove(req.Key)
+	case TouchAction:
+		dataStorage.Touch(req.Key)
+	case types.InsertBefore, types.InsertAfter:
+		if _, err := applyInsert(dataStorage, req); err != nil {
+			return err
+		}
+	case types.MoveToFront, types.MoveToBack:
+		applyMove(dataStorage, req)
+	case types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap, types.RemoveIfValue:
+		applyConditional(dataStorage, req)
+	case types.Batch:
+		for _, op := range req.Ops {
+			if err := applyRecord(dataStorage, op); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unexpected action %q", req.Action)
+	}
+	return nil
+}
+
+// persist appends the mutation to the write-ahead log if it is enabled.
+// The operations of the batch are persisted with it as a single record.
+func (p *processor) persist(req types.Request) error {
+	if p.wal == nil || p.inBatch {
+		return nil
+	}
+	if _, err := p.wal.Append(req); err != nil {
+		return fmt.Errorf("failed to persist the %s request: %v", req.Action, err)
+	}
+	p.mutationsSinceSnapshot++
+	return nil
+}
+
+// takeSnapshot copies the map content and writes it in background, so the
+// processing isn't blocked. Only one snapshot is written at a time.
+func (p *processor) takeSnapshot() {
+	if p.snapshots == nil || p.wal == nil {
+		return
+	}
+	if p.snapshotInProgress != nil {
+		select {
+		case <-p.snapshotInProgress:
+		default:
+			// The previous snapshot isn't written yet
+			return
+		}
+	}
+
+	// The map is changed by this goroutine only, so the copy matches the last record
+	snap := snapshot.Snapshot{
+		Seq:      p.wal.LastSeq(),
+		Revision: p.dataStorage.Revision(),
+		Items:    p.dataStorage.GetAll(),
+		Expiries: p.dataStorage.Expiries(),
+		Versions: p.dataStorage.Versions(),
+	}
+	p.mutationsSinceSnapshot = 0
+
+	inProgress := make(chan struct{})
+	p.snapshotInProgress = inProgress
+	p.snapshotWG.Add(1)
+	go func() {
+		defer p.snapshotWG.Done()
+		defer close(inProgress)
+
+		// The records up to the snapshot must be on the disk before it, otherwise
+		// the log recovered after a crash could end before the snapshot
+		if err := p.wal.Sync(); err != nil {
+			log.Printf("Failed to sync write-ahead log before snapshot %d: %v", snap.Seq, err)
+			return
+		}
+		if err := p.snapshots.Save(snap); err != nil {
+			log.Printf("Failed to save snapshot %d: %v", snap.Seq, err)
+			return
+		}
+		if err := p.wal.Truncate(snap.Seq); err != nil {
+			log.Printf("Failed to truncate write-ahead log: %v", err)
+		}
+	}()
+}
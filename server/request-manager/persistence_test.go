@@ -0,0 +1,138 @@
+package requestmanager
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"server/logger"
+	"testing"
+
+	orderermap "server/orderer-map"
+	"server/snapshot"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_SnapshotRecover(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	// Every record goes to its own segment, so the covered ones can be removed
+	walOptions := wal.Options{Sync: wal.SyncNever, SegmentSize: 1}
+	writeAheadLog, err := wal.Open(dir+"/wal", walOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+	store, err := snapshot.NewStore(dir+"/snapshots", 2)
+	if err != nil {
+		t.Fatalf("Error creating snapshot store: %v", err)
+	}
+
+	reqs := make(chan types.Request)
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithWAL(writeAheadLog), WithSnapshots(store, 3, 0))
+		close(done)
+	}()
+
+	reqs <- types.Request{Action: types.AddItem, Key: "a", Value: "1"}
+	reqs <- types.Request{Action: types.AddItem, Key: "b", Value: "2"}
+	reqs <- types.Request{Action: types.AddItem, Key: "c", Value: "3"}
+	reqs <- types.Request{Action: types.RemoveItem, Key: "a"}
+	reqs <- types.Request{Action: types.AddItem, Key: "a", Value: "4"}
+	close(reqs)
+	<-done
+	// Wait for the queued log writes
+	myLogger.Flush()
+	writeAheadLog.Close()
+
+	snap, err := store.Latest()
+	if err != nil || snap == nil {
+		t.Fatalf("Expected a snapshot to be saved, got %v, %v", snap, err)
+	}
+	if snap.Seq != 3 {
+		t.Errorf("Expected the snapshot after 3 mutations, got %d", snap.Seq)
+	}
+
+	// Restart from the snapshot and the rest of the log
+	writeAheadLog, err = wal.Open(dir+"/wal", walOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
+	}
+	defer writeAheadLog.Close()
+
+	// The records covered by the snapshot are truncated
+	if err := writeAheadLog.Replay(0, func(wal.Record) error { return nil }); err == nil {
+		t.Errorf("Expected the log records covered by the snapshot to be removed")
+	}
+
+	dataStorage := orderermap.NewOrderedMap()
+	if err := Recover(dataStorage, store, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering: %v", err)
+	}
+
+	expected := orderermap.Entries[string, string]{{Key: "b", Value: "2"}, {Key: "c", Value: "3"}, {Key: "a", Value: "4"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Recovered items = %v, expected %v", items, expected)
+	}
+	// The revision continues from the one the snapshot was taken at
+	if revision := dataStorage.Revision(); revision != 5 {
+		t.Errorf("Recovered revision = %d, expected 5", revision)
+	}
+
+	// The watchers resume with the changes replayed from the log numbered as before the restart
+	if _, err := dataStorage.Subscribe(2, nil); !errors.Is(err, orderermap.ErrHistoryCompacted) {
+		t.Errorf("Expected the changes before the snapshot to be unknown, got %v", err)
+	}
+	subscription, err := dataStorage.Subscribe(3, nil)
+	if err != nil {
+		t.Fatalf("Error subscribing after the snapshot: %v", err)
+	}
+	defer subscription.Close()
+	for _, expected := range []orderermap.Event[string, string]{
+		{Seq: 4, Type: orderermap.Removed, Key: "a", Value: "1"},
+		{Seq: 5, Type: orderermap.Added, Key: "a", Value: "4"},
+	} {
+		if event := <-subscription.Events(); event != expected {
+			t.Errorf("Event = %+v, expected %+v", event, expected)
+		}
+	}
+}
+
+func TestCheckSettings(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "settings.json")
+
+	// The settings are saved on the first start
+	if err := CheckSettings(filename, orderermap.NewOrderedMap(orderermap.WithCapacity(2, orderermap.LRU))); err != nil {
+		t.Fatalf("Error saving settings: %v", err)
+	}
+	if err := CheckSettings(filename, orderermap.NewOrderedMap(orderermap.WithCapacity(2, orderermap.LRU))); err != nil {
+		t.Errorf("Expected the same settings to be accepted, got %v", err)
+	}
+
+	// The evictions would differ from the persisted ones
+	for _, dataStorage := range []*orderermap.StringMap{
+		orderermap.NewOrderedMap(orderermap.WithCapacity(3, orderermap.LRU)),
+		orderermap.NewOrderedMap(orderermap.WithCapacity(2, orderermap.FIFO)),
+		orderermap.NewOrderedMap(),
+	} {
+		if err := CheckSettings(filename, dataStorage); err == nil {
+			t.Errorf("Expected %d max items and %s eviction to be rejected", dataStorage.Capacity(), dataStorage.EvictionPolicy())
+		}
+	}
+}
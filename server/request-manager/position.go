@@ -0,0 +1,111 @@
+package requestmanager
+
+import (
+	"fmt"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// insert puts the item before or after the mark item. Found is false if the mark doesn't exist.
+func (p *processor) insert(req types.Request) (types.Response, error) {
+	var resp types.Response
+	if req.Key == req.Mark {
+		resp.Error = "the key and the mark must differ"
+		p.log(req, logger.Entry{
+			Level:   logger.Warn,
+			Result:  "invalid mark",
+			Message: fmt.Sprintf("[%s] Key %s is the mark itself", req.Action, req.Key),
+		})
+		return resp, nil
+	}
+	if !p.setExpiresAt(&req, &resp) {
+		return resp, nil
+	}
+
+	if _, exists := p.dataStorage.Peek(req.Mark); !exists {
+		p.log(req, logger.Entry{
+			Level:   logger.Info,
+			Value:   req.Value,
+			Result:  "mark not found",
+			Message: fmt.Sprintf("[%s] Mark key %s doesn't exist", req.Action, req.Mark),
+		})
+		return resp, nil
+	}
+	if err := p.persist(req); err != nil {
+		p.log(req, logger.Entry{
+			Level:   logger.Error,
+			Value:   req.Value,
+			Result:  ResultFailed,
+			Message: fmt.Sprintf("[%s] Failed to insert key %s: %v", req.Action, req.Key, err),
+		})
+		return resp, err
+	}
+
+	evicted, _ := applyInsert(p.dataStorage, req)
+	resp.Found = true
+	position := "before"
+	if req.Action == types.InsertAfter {
+		position = "after"
+	}
+	p.log(req, logger.Entry{
+		Level:   logger.Info,
+		Value:   req.Value,
+		Result:  "inserted",
+		Message: fmt.Sprintf("[%s] Inserted key %s with value %s %s key %s", req.Action, req.Key, req.Value, position, req.Mark),
+	})
+	p.logEvicted(req, evicted)
+	return resp, nil
+}
+
+// move moves the item to the head or the tail. Found is false if the key doesn't exist.
+func (p *processor) move(req types.Request) (types.Response, error) {
+	var resp types.Response
+	if _, exists := p.dataStorage.Peek(req.Key); !exists {
+		p.log(req, logger.Entry{
+			Level:   logger.Info,
+			Result:  "not found",
+			Message: fmt.Sprintf("[%s] Key %s doesn't exist", req.Action, req.Key),
+		})
+		return resp, nil
+	}
+	if err := p.persist(req); err != nil {
+		p.log(req, logger.Entry{
+			Level:   logger.Error,
+			Result:  ResultFailed,
+			Message: fmt.Sprintf("[%s] Failed to move key %s: %v", req.Action, req.Key, err),
+		})
+		return resp, err
+	}
+
+	applyMove(p.dataStorage, req)
+	resp.Found = true
+	end := "front"
+	if req.Action == types.MoveToBack {
+		end = "back"
+	}
+	p.log(req, logger.Entry{
+		Level:   logger.Info,
+		Result:  "moved",
+		Message: fmt.Sprintf("[%s] Moved key %s to the %s", req.Action, req.Key, end),
+	})
+	return resp, nil
+}
+
+// applyInsert inserts the item of the request, it's used by the processing and the recovery.
+func applyInsert(dataStorage *orderermap.StringMap, req types.Request) (orderermap.Entries[string, string], error) {
+	if req.Action == types.InsertAfter {
+		return dataStorage.InsertAfter(req.Mark, req.Key, req.Value, addOptions(req)...)
+	}
+	return dataStorage.InsertBefore(req.Mark, req.Key, req.Value, addOptions(req)...)
+}
+
+// applyMove moves the item of the request, it's used by the processing and the recovery.
+func applyMove(dataStorage *orderermap.StringMap, req types.Request) bool {
+	if req.Action == types.MoveToBack {
+		return dataStorage.MoveToBack(req.Key)
+	}
+	return dataStorage.MoveToFront(req.Key)
+}
@@ -0,0 +1,108 @@
+package requestmanager
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"server/logger"
+	"strings"
+	"testing"
+
+	orderermap "server/orderer-map"
+	"server/wal"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_Positional(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	writeAheadLog, err := wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	dataStorage := orderermap.NewOrderedMap()
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 10)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder), WithStorage(dataStorage), WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	requests := []struct {
+		req      types.Request
+		expected types.Response
+	}{
+		{types.Request{Action: types.AddItem, Key: "a", Value: "1"}, types.Response{}},
+		{types.Request{Action: types.AddItem, Key: "c", Value: "3"}, types.Response{}},
+		{types.Request{Action: types.InsertBefore, Mark: "c", Key: "b", Value: "2"}, types.Response{Found: true}},
+		{types.Request{Action: types.InsertAfter, Mark: "c", Key: "d", Value: "4"}, types.Response{Found: true}},
+		{types.Request{Action: types.InsertAfter, Mark: "x", Key: "e", Value: "5"}, types.Response{}},
+		{types.Request{Action: types.InsertAfter, Mark: "a", Key: "a", Value: "5"}, types.Response{Error: "the key and the mark must differ"}},
+		{types.Request{Action: types.MoveToFront, Key: "d"}, types.Response{Found: true}},
+		{types.Request{Action: types.MoveToBack, Key: "a"}, types.Response{Found: true}},
+		{types.Request{Action: types.MoveToBack, Key: "x"}, types.Response{}},
+	}
+	for _, r := range requests {
+		reqs <- r.req
+	}
+	close(reqs)
+	<-done
+	myLogger.Flush()
+	writeAheadLog.Close()
+
+	for i, r := range requests {
+		if resp := <-responder.responses; !reflect.DeepEqual(resp, r.expected) {
+			t.Errorf("Response %d to %s = %+v, expected %+v", i, r.req.Action, resp, r.expected)
+		}
+	}
+
+	expected := orderermap.Entries[string, string]{{Key: "d", Value: "4"}, {Key: "b", Value: "2"}, {Key: "c", Value: "3"}, {Key: "a", Value: "1"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Items = %v, expected %v", items, expected)
+	}
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	for _, line := range []string{
+		"#3 [insertBefore] Inserted key b with value 2 before key c",
+		"#5 [insertAfter] Mark key x doesn't exist",
+		"#7 [moveToFront] Moved key d to the front",
+	} {
+		if !strings.Contains(string(fileContent), line+"\n") {
+			t.Errorf("Expected %q in the log, got %q", line, fileContent)
+		}
+	}
+
+	// The positions are recovered from the log
+	writeAheadLog, err = wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
+	}
+	defer writeAheadLog.Close()
+
+	recovered := orderermap.NewOrderedMap()
+	if err := Recover(recovered, nil, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering from write-ahead log: %v", err)
+	}
+	if items := recovered.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Recovered items = %v, expected %v", items, expected)
+	}
+}
@@ -3,42 +3,381 @@ package requestmanager
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	logger "server/logger"
+	"sync"
+	"time"
 
 	orderermap "server/orderer-map"
+	"server/snapshot"
+	"server/wal"
 
 	types "github.com/enriquenc/orderer-map-client-server-go/shared"
 )
 
-func ProcessRequests(reqs <-chan types.Request, logger *logger.Logger) {
+// Responder sends the response of a processed request back to its sender.
+type Responder interface {
+	Reply(req types.Request, resp types.Response) error
+}
+
+// Acknowledger settles the consumed requests once they are processed.
+type Acknowledger interface {
+	Ack(req types.Request) error
+	Nack(req types.Request, requeue bool) error
+}
+
+// Results of the logged operations which weren't applied to the map.
+const (
+	ResultDuplicate = "duplicate"
+	ResultFailed    = "failed"
+)
+
+// requeueDelay is the pause before the request which failed to be applied is returned
+// to the queue, so a persistent failure doesn't spin the loop. The loop keeps handling
+// the expiries and the snapshots meanwhile.
+const requeueDelay = 100 * time.Millisecond
+
+// Option configures ProcessRequests.
+type Option func(*options)
+
+type options struct {
+	responder    Responder
+	acknowledger Acknowledger
+	dataStorage  *orderermap.StringMap
+	wal          *wal.Log
+	snapshots    *snapshot.Store
+	// Snapshot creation triggers
+	snapshotEvery    uint64
+	snapshotInterval time.Duration
+	dedupWindow      int
+}
+
+// WithResponder makes ProcessRequests answer every processed request.
+func WithResponder(responder Responder) Option {
+	return func(o *options) {
+		o.responder = responder
+	}
+}
+
+// WithAcknowledger makes ProcessRequests acknowledge every request after it's
+// applied to the map and persisted. A request which failed to be applied is
+// rejected and returned to the queue to be processed again.
+func WithAcknowledger(acknowledger Acknowledger) Option {
+	return func(o *options) {
+		o.acknowledger = acknowledger
+	}
+}
+
+// WithStorage makes ProcessRequests use the given map, e.g. restored by Recover.
+func WithStorage(dataStorage *orderermap.StringMap) Option {
+	return func(o *options) {
+		o.dataStorage = dataStorage
+	}
+}
+
+func ProcessRequests(reqs <-chan types.Request, logger *logger.Logger, opts ...Option) {
+	p := &processor{logger: logger}
+	p.options.dedupWindow = DefaultDedupWindow
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+
 	// Create an OrderedMap to store the processed requests
-	dataStorage := orderermap.NewOrderedMap()
-
-	for req := range reqs {
-		// Processing of requests
-		switch req.Action {
-		case types.AddItem:
-			dataStorage.Add(req.Key, req.Value)
-			go logger.Log(fmt.Sprintf("[add] Added key %s with value %s", req.Key, req.Value))
-
-		case types.RemoveItem:
-			exists := dataStorage.Remove(req.Key)
-			if exists {
-				go logger.Log(fmt.Sprintf("[remove] key %s", req.Key))
-			} else {
-				go logger.Log(fmt.Sprintf("[remove] key %s doesn't exist", req.Key))
+	p.dataStorage = p.options.dataStorage
+	if p.dataStorage == nil {
+		p.dataStorage = orderermap.NewOrderedMap()
+	}
+
+	p.dedup = newDedupWindow(p.options.dedupWindow)
+	p.seedDedupWindow()
+
+	var snapshotTicker <-chan time.Time
+	if p.snapshots != nil && p.wal != nil && p.snapshotInterval > 0 {
+		ticker := time.NewTicker(p.snapshotInterval)
+		defer ticker.Stop()
+		snapshotTicker = ticker.C
+	}
+	// Let the snapshot being written to finish
+	defer p.snapshotWG.Wait()
+
+	// The items recovered expired are removed before any request
+	p.expire()
+	expiryTimer := p.expiryTimer()
+	defer func() {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+	}()
+
+	// The failed requests waiting to be requeued are returned before exiting
+	defer func() {
+		if p.requeueTimer != nil {
+			p.requeueTimer.Stop()
+			p.requeue()
+		}
+	}()
+
+	for {
+		var requeueTimer <-chan time.Time
+		if p.requeueTimer != nil {
+			requeueTimer = p.requeueTimer.C
+		}
+
+		select {
+		case req, ok := <-reqs:
+			if !ok {
+				return
+			}
+
+			// The items expired before the request arrived are removed first
+			p.expire()
+			p.handleRequest(req)
+
+			if p.snapshotEvery > 0 && p.mutationsSinceSnapshot >= p.snapshotEvery {
+				p.takeSnapshot()
 			}
-		case types.GetItem:
-			value, exists := dataStorage.Get(req.Key)
-			if exists {
-				go logger.Log(fmt.Sprintf("[get] Got key %s with value %s", req.Key, value))
-			} else {
-				go logger.Log(fmt.Sprintf("[get] Key %s doesn't exist", req.Key))
+		case <-expiryTimer:
+			p.expire()
+		case <-snapshotTicker:
+			if p.mutationsSinceSnapshot > 0 {
+				p.takeSnapshot()
 			}
-		case types.GetAll:
-			items := dataStorage.GetAll()
-			b, _ := json.Marshal(items)
-			go logger.Log(fmt.Sprintf("[getAll] All values %s", string(b)))
+		case <-requeueTimer:
+			p.requeue()
 		}
+		expiryTimer = p.expiryTimer()
+	}
+}
+
+// processor holds the state of the requests processing loop.
+type processor struct {
+	options
+	logger *logger.Logger
+	dedup  *dedupWindow
+	// Position of the request being handled in the processing order
+	seq uint64
+	// Time the handling of the current request started at
+	started time.Time
+	// timer fires at the earliest expiry time of the items
+	timer *time.Timer
+	// requeued are the failed requests returned to the queue when requeueTimer fires
+	requeued     []types.Request
+	requeueTimer *time.Timer
+	// inBatch is set while the operations of the batch are applied,
+	// their log entries are held in batchLog until it's persisted
+	inBatch  bool
+	batchLog []logger.Entry
+
+	mutationsSinceSnapshot uint64
+	snapshotInProgress     chan struct{}
+	snapshotWG             sync.WaitGroup
+}
+
+// handleRequest processes the request unless it's a duplicate, answers and settles it.
+func (p *processor) handleRequest(req types.Request) {
+	p.seq++
+	p.started = time.Now()
+	resp, duplicate := p.dedup.Get(req.ID)
+	var err error
+	if duplicate {
+		p.log(req, logger.Entry{
+			Level:   logger.Info,
+			Result:  ResultDuplicate,
+			Message: fmt.Sprintf("[%s] Duplicate request %s for key %s, replying with the original result", req.Action, req.ID, req.Key),
+		})
+	} else {
+		resp, err = p.processRequest(req)
+	}
+	if err != nil {
+		if p.acknowledger != nil {
+			p.requeueLater(req)
+			return
+		}
+		resp = types.Response{Error: err.Error()}
+	} else {
+		p.dedup.Add(req.ID, resp)
+	}
+
+	if p.responder != nil {
+		if err := p.responder.Reply(req, resp); err != nil {
+			log.Printf("Failed to reply to %s request: %v", req.Action, err)
+		}
+	}
+	if p.acknowledger != nil {
+		if err := p.acknowledger.Ack(req); err != nil {
+			log.Printf("Failed to acknowledge %s request: %v", req.Action, err)
+		}
+	}
+}
+
+// requeueLater returns the failed request to the queue after requeueDelay. The following
+// requests aren't delivered until it's settled, so it's delivered again before them.
+func (p *processor) requeueLater(req types.Request) {
+	p.requeued = append(p.requeued, req)
+	if p.requeueTimer == nil {
+		p.requeueTimer = time.NewTimer(requeueDelay)
+	}
+}
+
+// requeue returns the failed requests to the queue.
+func (p *processor) requeue() {
+	for _, req := range p.requeued {
+		if err := p.acknowledger.Nack(req, true); err != nil {
+			log.Printf("Failed to requeue %s request: %v", req.Action, err)
+		}
+	}
+	p.requeued = nil
+	p.requeueTimer = nil
+}
+
+// processRequest applies the request to the map. An error is returned
+// if the request wasn't applied and can be retried.
+func (p *processor) processRequest(req types.Request) (types.Response, error) {
+	var resp types.Response
+	dataStorage := p.dataStorage
+
+	// Processing of requests
+	switch req.Action {
+	case types.AddItem:
+		if !p.setExpiresAt(&req, &resp) {
+			break
+		}
+		if err := p.persist(req); err != nil {
+			p.log(req, logger.Entry{
+				Level:   logger.Error,
+				Value:   req.Value,
+				Result:  ResultFailed,
+				Message: fmt.Sprintf("[add] Failed to add key %s: %v", req.Key, err),
+			})
+			return resp, err
+		}
+		evicted := dataStorage.Add(req.Key, req.Value, addOptions(req)...)
+		message := fmt.Sprintf("[add] Added key %s with value %s", req.Key, req.Value)
+		if req.ExpiresAt != nil {
+			message += fmt.Sprintf(" expiring in %s", req.TTL)
+		}
+		p.log(req, logger.Entry{
+			Level:   logger.Info,
+			Value:   req.Value,
+			Result:  "added",
+			Message: message,
+		})
+		p.logEvicted(req, evicted)
+
+	case types.RemoveItem:
+		_, exists := dataStorage.Peek(req.Key)
+		if exists {
+			if err := p.persist(req); err != nil {
+				p.log(req, logger.Entry{
+					Level:   logger.Error,
+					Result:  ResultFailed,
+					Message: fmt.Sprintf("[remove] Failed to remove key %s: %v", req.Key, err),
+				})
+				return resp, err
+			}
+			dataStorage.Remove(req.Key)
+		}
+		resp.Found = exists
+		if exists {
+			p.log(req, logger.Entry{
+				Level:   logger.Info,
+				Result:  "removed",
+				Message: fmt.Sprintf("[remove] key %s", req.Key),
+			})
+		} else {
+			p.log(req, logger.Entry{
+				Level:   logger.Info,
+				Result:  "not found",
+				Message: fmt.Sprintf("[remove] key %s doesn't exist", req.Key),
+			})
+		}
+	case types.GetItem:
+		if req.Revision != 0 {
+			return p.getAtRevision(req), nil
+		}
+		value, exists := dataStorage.Peek(req.Key)
+		if exists && dataStorage.EvictionPolicy() == orderermap.LRU {
+			if err := p.touch(req); err != nil {
+				return resp, err
+			}
+		}
+		resp.Found = exists
+		resp.Value = value
+		resp.Version, _ = dataStorage.Version(req.Key)
+		resp.Revision = dataStorage.Revision()
+		if exists {
+			// The access reorders the LRU items, it's logged for the replay as the mutations are
+			level := logger.Debug
+			if dataStorage.EvictionPolicy() == orderermap.LRU {
+				level = logger.Info
+			}
+			p.log(req, logger.Entry{
+				Level:   level,
+				Value:   value,
+				Result:  "found",
+				Message: fmt.Sprintf("[get] Got key %s with value %s", req.Key, value),
+			})
+		} else {
+			p.log(req, logger.Entry{
+				Level:   logger.Debug,
+				Result:  "not found",
+				Message: fmt.Sprintf("[get] Key %s doesn't exist", req.Key),
+			})
+		}
+	case types.GetAll:
+		if req.Revision != 0 {
+			return p.getAllAtRevision(req), nil
+		}
+		items := dataStorage.GetAll()
+		resp.Revision = dataStorage.Revision()
+		resp.Items = make(types.Items, 0, len(items))
+		for _, item := range items {
+			resp.Items = append(resp.Items, types.Item{Key: item.Key, Value: item.Value})
+		}
+		b, _ := json.Marshal(items)
+		p.log(req, logger.Entry{
+			Level:   logger.Debug,
+			Value:   string(b),
+			Result:  fmt.Sprintf("%d items", len(items)),
+			Message: fmt.Sprintf("[getAll] All values %s", string(b)),
+		})
+	case types.GetPage, types.GetHead, types.GetTail:
+		resp = p.page(req)
+	case types.GetAt, types.IndexOf:
+		resp = p.position(req)
+	case types.InsertBefore, types.InsertAfter:
+		return p.insert(req)
+	case types.MoveToFront, types.MoveToBack:
+		return p.move(req)
+	case types.AddIfAbsent, types.UpdateIfPresent, types.CompareAndSwap, types.RemoveIfValue:
+		return p.conditional(req)
+	case types.Batch:
+		return p.batch(req)
+	default:
+		resp.Error = fmt.Sprintf("unknown action %q", req.Action)
+		p.log(req, logger.Entry{
+			Level:   logger.Warn,
+			Result:  "unknown action",
+			Message: fmt.Sprintf("[%s] Unknown action", req.Action),
+		})
+	}
+
+	return resp, nil
+}
+
+// log writes the entry of the current request tagged with its position in the processing order.
+// The logger queues it, so the processing loop isn't blocked by the file writes.
+func (p *processor) log(req types.Request, entry logger.Entry) {
+	entry.Seq = p.seq
+	entry.Action = req.Action
+	entry.Key = req.Key
+	entry.Mark = req.Mark
+	entry.RequestID = req.ID
+	entry.Revision = p.dataStorage.Revision()
+	entry.Latency = time.Since(p.started)
+	if p.inBatch {
+		p.batchLog = append(p.batchLog, entry)
+		return
 	}
+	p.logger.LogEntry(entry)
 }
@@ -2,9 +2,14 @@ package requestmanager
 
 import (
 	// other imports
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"server/logger"
+	orderermap "server/orderer-map"
+	"server/wal"
 	"strings"
 	"testing"
 	"time"
@@ -53,6 +58,7 @@ func TestProcessRequests_GetItem(t *testing.T) {
 
 	// Wait for ProcessRequests to finish
 	time.Sleep(time.Millisecond * 100)
+	myLogger.Flush()
 
 	// Read the logger output from the file
 	fileContent, err := ioutil.ReadFile(file.Name())
@@ -60,18 +66,11 @@ func TestProcessRequests_GetItem(t *testing.T) {
 		t.Fatalf("Error reading file: %v", err)
 	}
 
-	// As the result is asynchronous, we cannot guarantee the exact order of the messages in the log file.
-	// Therefore, we need to have two expected results, what checks just for existence
-	// Verify that the logger output contains the expected messages
-	expectedAddMsg := "[add] Added key foo with value bar\n"
-	expectedGetMsg := "[get] Got key foo with value bar\n"
-	if !strings.Contains(string(fileContent), expectedAddMsg) {
-		t.Errorf("Expected logger output to contain %q, but got %q", expectedAddMsg, string(fileContent))
-	}
-	if !strings.Contains(string(fileContent), expectedGetMsg) {
-		t.Errorf("Expected logger output to contain %q, but got %q", expectedGetMsg, string(fileContent))
+	// Verify that the logger output contains the messages in the processing order
+	expected := "#1 [add] Added key foo with value bar\n#2 [get] Got key foo with value bar\n"
+	if string(fileContent) != expected {
+		t.Errorf("Expected logger output %q, but got %q", expected, string(fileContent))
 	}
-
 }
 
 func TestProcessRequests_RemoveItem_NotExist(t *testing.T) {
@@ -107,6 +106,7 @@ func TestProcessRequests_RemoveItem_NotExist(t *testing.T) {
 
 	// Wait for ProcessRequests to finish
 	time.Sleep(time.Millisecond * 100)
+	myLogger.Flush()
 
 	// Read the logger output from the file
 	fileContent, err := ioutil.ReadFile(file.Name())
@@ -115,7 +115,7 @@ func TestProcessRequests_RemoveItem_NotExist(t *testing.T) {
 	}
 
 	// Verify that the logger output contains the expected message
-	expected := "[remove] key nonexistent doesn't exist\n"
+	expected := "#1 [remove] key nonexistent doesn't exist\n"
 	if string(fileContent) != expected {
 		t.Errorf("Expected logger output %q, but got %q", expected, string(fileContent))
 	}
@@ -162,6 +162,7 @@ func TestProcessRequests_AddItem_Duplicate(t *testing.T) {
 
 	// Wait for ProcessRequests to finish
 	time.Sleep(time.Millisecond * 100)
+	myLogger.Flush()
 
 	// Read the logger output from the file
 	fileContent, err := ioutil.ReadFile(file.Name())
@@ -170,7 +171,7 @@ func TestProcessRequests_AddItem_Duplicate(t *testing.T) {
 	}
 
 	// Verify that the logger output contains the expected message
-	expected := "[add] Added key foo with value bar\n[add] Added key foo with value baz\n"
+	expected := "#1 [add] Added key foo with value bar\n#2 [add] Added key foo with value baz\n"
 	if string(fileContent) != expected {
 		t.Errorf("Expected logger output %q, but got %q", expected, string(fileContent))
 	}
@@ -220,6 +221,7 @@ func TestProcessRequests_GetAll(t *testing.T) {
 
 	// Wait for ProcessRequests to finish
 	time.Sleep(time.Millisecond * 100)
+	myLogger.Flush()
 
 	// Read the logger output from the file
 	fileContent, err := ioutil.ReadFile(file.Name())
@@ -227,19 +229,310 @@ func TestProcessRequests_GetAll(t *testing.T) {
 		t.Fatalf("Error reading file: %v", err)
 	}
 
-	// Verify that the logger output contains the expected messages
-	expectedAddMsg1 := "[add] Added key foo with value bar\n"
-	expectedAddMsg2 := "[add] Added key baz with value qux\n"
-	expectedGetAllMsg := "[getAll] All values [\"foo=bar\",\"baz=qux\"]\n"
+	// Verify that the logger output contains the messages in the processing order
+	expected := "#1 [add] Added key foo with value bar\n" +
+		"#2 [add] Added key baz with value qux\n" +
+		"#3 [getAll] All values {\"foo\":\"bar\",\"baz\":\"qux\"}\n"
+	if string(fileContent) != expected {
+		t.Errorf("Expected logger output %q, but got %q", expected, string(fileContent))
+	}
+}
+
+func TestProcessRequests_LogOrder(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	reqs := make(chan types.Request)
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger)
+		close(done)
+	}()
+
+	// Every key is added and then removed by the following request
+	var expected strings.Builder
+	for i := 1; i <= 500; i++ {
+		key := fmt.Sprintf("key%d", (i+1)/2%7)
+		value := fmt.Sprint(i)
+		if i%2 == 1 {
+			reqs <- types.Request{Action: types.AddItem, Key: key, Value: value}
+			fmt.Fprintf(&expected, "#%d [add] Added key %s with value %s\n", i, key, value)
+		} else {
+			reqs <- types.Request{Action: types.RemoveItem, Key: key}
+			fmt.Fprintf(&expected, "#%d [remove] key %s\n", i, key)
+		}
+	}
+	close(reqs)
+	<-done
+	myLogger.Flush()
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+	if string(fileContent) != expected.String() {
+		t.Errorf("Log order doesn't match the request order:\n%s", fileContent)
+	}
+}
+
+type recordingResponder struct {
+	responses chan types.Response
+}
+
+func (r *recordingResponder) Reply(req types.Request, resp types.Response) error {
+	r.responses <- resp
+	return nil
+}
+
+func TestProcessRequests_Responses(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// Create a logger
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 5)}
+
+	go ProcessRequests(reqs, myLogger, WithResponder(responder))
+
+	reqs <- types.Request{Action: types.AddItem, Key: "foo", Value: "bar"}
+	reqs <- types.Request{Action: types.GetItem, Key: "foo"}
+	reqs <- types.Request{Action: types.GetItem, Key: "baz"}
+	reqs <- types.Request{Action: types.GetAll}
+	reqs <- types.Request{Action: "unknown"}
+	close(reqs)
+
+	expected := []types.Response{
+		{},
+		{Found: true, Value: "bar", Version: 1, Revision: 1},
+		{Found: false, Revision: 1},
+		{Items: types.Items{{Key: "foo", Value: "bar"}}, Revision: 1},
+		{Error: "unknown action \"unknown\""},
+	}
+	for i, want := range expected {
+		select {
+		case got := <-responder.responses:
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Response %d = %+v, expected %+v", i, got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for response %d", i)
+		}
+	}
+}
+
+// recordingBroker records the order of the replies and acknowledgements.
+type recordingBroker struct {
+	events chan string
+}
+
+func (b *recordingBroker) Reply(req types.Request, resp types.Response) error {
+	b.events <- "reply " + req.Key
+	return nil
+}
+
+func (b *recordingBroker) Ack(req types.Request) error {
+	b.events <- "ack " + req.Key
+	return nil
+}
+
+func (b *recordingBroker) Nack(req types.Request, requeue bool) error {
+	b.events <- fmt.Sprintf("nack %s requeue=%v", req.Key, requeue)
+	return nil
+}
+
+func TestProcessRequests_Acknowledgements(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	// The closed log fails every append
+	writeAheadLog, err := wal.Open(t.TempDir(), wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+	writeAheadLog.Close()
+
+	reqs := make(chan types.Request)
+	broker := &recordingBroker{events: make(chan string, 10)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(broker), WithAcknowledger(broker), WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	// Reads are answered and acknowledged
+	reqs <- types.Request{Action: types.GetItem, Key: "a"}
+	// Mutations which can't be persisted are requeued without the answer
+	reqs <- types.Request{Action: types.AddItem, Key: "b", Value: "1"}
+	// The loop isn't blocked until the failed request is requeued
+	reqs <- types.Request{Action: types.GetItem, Key: "c"}
+	close(reqs)
+	<-done
+	// Wait for the queued log writes
+	myLogger.Flush()
+	close(broker.events)
+
+	var events []string
+	for event := range broker.events {
+		events = append(events, event)
+	}
+	expected := []string{"reply a", "ack a", "reply c", "ack c", "nack b requeue=true"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Events = %v, expected %v", events, expected)
+	}
+}
+
+func TestProcessRequests_JSONLog(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// The reads are logged at the debug level
+	myLogger, err := logger.NewLogger(file.Name(), logger.WithFormat(logger.JSON), logger.WithLevel(logger.Info))
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	reqs := make(chan types.Request)
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger)
+		close(done)
+	}()
+
+	reqs <- types.Request{ID: "1", Action: types.AddItem, Key: "foo", Value: "bar"}
+	reqs <- types.Request{ID: "2", Action: types.GetItem, Key: "foo"}
+	reqs <- types.Request{ID: "3", Action: types.RemoveItem, Key: "baz"}
+	close(reqs)
+	<-done
+	myLogger.Flush()
+
+	fileContent, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("Error reading file: %v", err)
+	}
+
+	type entry struct {
+		Level     string `json:"level"`
+		Seq       uint64 `json:"seq"`
+		Action    string `json:"action"`
+		Key       string `json:"key"`
+		Value     string `json:"value"`
+		Result    string `json:"result"`
+		RequestID string `json:"request_id"`
+	}
+	var entries []entry
+	for _, line := range strings.Split(strings.TrimSpace(string(fileContent)), "\n") {
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Error decoding log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+
+	expected := []entry{
+		{Level: "info", Seq: 1, Action: types.AddItem, Key: "foo", Value: "bar", Result: "added", RequestID: "1"},
+		{Level: "info", Seq: 3, Action: types.RemoveItem, Key: "baz", Result: "not found", RequestID: "3"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Log entries = %+v, expected %+v", entries, expected)
+	}
+}
+
+func TestProcessRequests_WALRecover(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	dir := t.TempDir()
+	writeAheadLog, err := wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error opening write-ahead log: %v", err)
+	}
+
+	reqs := make(chan types.Request)
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithWAL(writeAheadLog))
+		close(done)
+	}()
+
+	reqs <- types.Request{Action: types.AddItem, Key: "a", Value: "1"}
+	reqs <- types.Request{Action: types.AddItem, Key: "b", Value: "2"}
+	reqs <- types.Request{Action: types.RemoveItem, Key: "a"}
+	reqs <- types.Request{Action: types.RemoveItem, Key: "c"}
+	reqs <- types.Request{Action: types.AddItem, Key: "a", Value: "3"}
+	reqs <- types.Request{Action: types.GetAll}
+	close(reqs)
+	<-done
 
-	if !strings.Contains(string(fileContent), expectedAddMsg1) {
-		t.Errorf("Expected logger output to contain %q, but got %q", expectedAddMsg1, string(fileContent))
+	// Only the applied mutations are persisted
+	if lastSeq := writeAheadLog.LastSeq(); lastSeq != 4 {
+		t.Errorf("Expected 4 records in the log, got %d", lastSeq)
 	}
-	if !strings.Contains(string(fileContent), expectedAddMsg2) {
-		t.Errorf("Expected logger output to contain %q, but got %q", expectedAddMsg2, string(fileContent))
+	writeAheadLog.Close()
+
+	// Restart from the log
+	writeAheadLog, err = wal.Open(dir, wal.DefaultOptions)
+	if err != nil {
+		t.Fatalf("Error reopening write-ahead log: %v", err)
 	}
-	if !strings.Contains(string(fileContent), expectedGetAllMsg) {
-		t.Errorf("Expected logger output to contain %q, but got %q", expectedGetAllMsg, string(fileContent))
+	defer writeAheadLog.Close()
+
+	dataStorage := orderermap.NewOrderedMap()
+	if err := Recover(dataStorage, nil, writeAheadLog); err != nil {
+		t.Fatalf("Error recovering from write-ahead log: %v", err)
 	}
 
+	expected := orderermap.Entries[string, string]{{Key: "b", Value: "2"}, {Key: "a", Value: "3"}}
+	if items := dataStorage.GetAll(); !reflect.DeepEqual(items, expected) {
+		t.Errorf("Recovered items = %v, expected %v", items, expected)
+	}
 }
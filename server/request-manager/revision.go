@@ -0,0 +1,95 @@
+package requestmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	logger "server/logger"
+	orderermap "server/orderer-map"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+// getAtRevision answers the get of the item as it was at the revision of the request.
+func (p *processor) getAtRevision(req types.Request) types.Response {
+	var resp types.Response
+	value, exists, err := p.dataStorage.GetAtRevision(req.Key, req.Revision)
+	if err != nil {
+		return p.revisionError(req, err)
+	}
+	resp.Found = exists
+	resp.Value = value
+	resp.Revision = req.Revision
+	if exists {
+		p.log(req, logger.Entry{
+			Level:   logger.Debug,
+			Value:   value,
+			Result:  "found",
+			Message: fmt.Sprintf("[get] Got key %s with value %s at revision %d", req.Key, value, req.Revision),
+		})
+	} else {
+		p.log(req, logger.Entry{
+			Level:   logger.Debug,
+			Result:  "not found",
+			Message: fmt.Sprintf("[get] Key %s doesn't exist at revision %d", req.Key, req.Revision),
+		})
+	}
+	return resp
+}
+
+// getAllAtRevision answers the getAll with the items as they were at the revision of the request.
+func (p *processor) getAllAtRevision(req types.Request) types.Response {
+	var resp types.Response
+	items, err := p.dataStorage.GetAllAtRevision(req.Revision)
+	if err != nil {
+		return p.revisionError(req, err)
+	}
+	resp.Items = make(types.Items, 0, len(items))
+	for _, item := range items {
+		resp.Items = append(resp.Items, types.Item{Key: item.Key, Value: item.Value})
+	}
+	resp.Revision = req.Revision
+	b, _ := json.Marshal(items)
+	p.log(req, logger.Entry{
+		Level:   logger.Debug,
+		Value:   string(b),
+		Result:  fmt.Sprintf("%d items", len(items)),
+		Message: fmt.Sprintf("[getAll] All values %s at revision %d", string(b), req.Revision),
+	})
+	return resp
+}
+
+// revisionError answers the read at the revision which isn't kept or wasn't reached yet.
+func (p *processor) revisionError(req types.Request, err error) types.Response {
+	var resp types.Response
+	if errors.Is(err, orderermap.ErrRevisionCompacted) {
+		resp.Error = fmt.Sprintf("revision %d is no longer kept, the oldest one is %d", req.Revision, p.dataStorage.OldestRevision())
+	} else {
+		resp.Error = fmt.Sprintf("revision %d is ahead of the current one %d", req.Revision, p.dataStorage.Revision())
+	}
+	p.log(req, logger.Entry{
+		Level:   logger.Debug,
+		Result:  "invalid revision",
+		Message: fmt.Sprintf("[%s] Invalid revision: %s", req.Action, resp.Error),
+	})
+	return resp
+}
+
+// pageAtRevision reads the page of the items as they were at the revision of the request
+// the same way the current items are paged.
+func pageAtRevision(dataStorage *orderermap.StringMap, req types.Request, limit int) (orderermap.Entries[string, string], bool, error) {
+	switch {
+	case req.Action == types.GetHead:
+		return dataStorage.PageAtRevision(req.Revision, 0, limit, false)
+	case req.Action == types.GetTail:
+		items, _, err := dataStorage.PageAtRevision(req.Revision, 0, limit, true)
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+		return items, false, err
+	case req.After != "":
+		return dataStorage.PageAfterAtRevision(req.Revision, req.After, limit, req.Reverse)
+	}
+	return dataStorage.PageAtRevision(req.Revision, req.Offset, limit, req.Reverse)
+}
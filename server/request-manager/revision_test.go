@@ -0,0 +1,96 @@
+package requestmanager
+
+import (
+	"os"
+	"reflect"
+	"server/logger"
+	"strconv"
+	"testing"
+
+	orderermap "server/orderer-map"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestProcessRequests_ReadAtRevision(t *testing.T) {
+	myLogger, err := logger.NewLogger(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 20)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder))
+		close(done)
+	}()
+
+	for i := 1; i <= 5; i++ {
+		reqs <- types.Request{Action: types.AddItem, Key: "k" + strconv.Itoa(i), Value: strconv.Itoa(i)}
+		<-responder.responses
+	}
+
+	item := func(i int, value string) types.Item {
+		return types.Item{Key: "k" + strconv.Itoa(i), Value: value}
+	}
+	requests := []struct {
+		req      types.Request
+		expected types.Response
+	}{
+		{types.Request{Action: types.GetPage, Limit: 2}, types.Response{Items: types.Items{item(1, "1"), item(2, "2")}, Next: "k2", Revision: 5}},
+		// The writers continue between the pages
+		{types.Request{Action: types.RemoveItem, Key: "k3"}, types.Response{Found: true}},
+		{types.Request{Action: types.AddItem, Key: "k4", Value: "40"}, types.Response{}},
+		{types.Request{Action: types.MoveToFront, Key: "k5"}, types.Response{Found: true}},
+		{types.Request{Action: types.GetPage, After: "k2", Limit: 2, Revision: 5}, types.Response{Items: types.Items{item(3, "3"), item(4, "4")}, Next: "k4", Revision: 5}},
+		{types.Request{Action: types.GetPage, After: "k4", Limit: 2, Revision: 5}, types.Response{Items: types.Items{item(5, "5")}, Revision: 5}},
+		{types.Request{Action: types.GetPage, Limit: 2, Reverse: true, Revision: 6}, types.Response{Items: types.Items{item(5, "5"), item(4, "4")}, Next: "k4", Revision: 6}},
+		{types.Request{Action: types.GetTail, Limit: 1, Revision: 7}, types.Response{Items: types.Items{item(5, "5")}, Revision: 7}},
+		{types.Request{Action: types.GetTail, Limit: 2, Revision: 7}, types.Response{Items: types.Items{item(4, "40"), item(5, "5")}, Revision: 7}},
+		{types.Request{Action: types.GetPage, Offset: 1, Limit: 2, Revision: 7}, types.Response{Items: types.Items{item(2, "2"), item(4, "40")}, Next: "k4", Revision: 7}},
+		{types.Request{Action: types.GetItem, Key: "k3", Revision: 5}, types.Response{Found: true, Value: "3", Revision: 5}},
+		{types.Request{Action: types.GetItem, Key: "k3", Revision: 6}, types.Response{Revision: 6}},
+		{types.Request{Action: types.GetAll, Revision: 7}, types.Response{Items: types.Items{item(1, "1"), item(2, "2"), item(4, "40"), item(5, "5")}, Revision: 7}},
+		{types.Request{Action: types.GetAll}, types.Response{Items: types.Items{item(5, "5"), item(1, "1"), item(2, "2"), item(4, "40")}, Revision: 8}},
+		{types.Request{Action: types.GetPage, After: "k3", Revision: 7}, types.Response{Error: "cursor key k3 doesn't exist"}},
+		{types.Request{Action: types.GetPage, Revision: 9}, types.Response{Error: "revision 9 is ahead of the current one 8"}},
+	}
+	for i, r := range requests {
+		reqs <- r.req
+		if resp := <-responder.responses; !reflect.DeepEqual(resp, r.expected) {
+			t.Errorf("Response %d to %+v = %+v, expected %+v", i, r.req, resp, r.expected)
+		}
+	}
+	close(reqs)
+	<-done
+}
+
+func TestProcessRequests_RevisionCompacted(t *testing.T) {
+	myLogger, err := logger.NewLogger(os.DevNull)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	reqs := make(chan types.Request)
+	responder := &recordingResponder{responses: make(chan types.Response, 20)}
+	done := make(chan struct{})
+	go func() {
+		ProcessRequests(reqs, myLogger, WithResponder(responder), WithStorage(orderermap.NewOrderedMap(orderermap.WithRetention(2))))
+		close(done)
+	}()
+
+	for i := 1; i <= 5; i++ {
+		reqs <- types.Request{Action: types.AddItem, Key: "k" + strconv.Itoa(i), Value: strconv.Itoa(i)}
+		<-responder.responses
+	}
+	reqs <- types.Request{Action: types.GetAll, Revision: 2}
+	expected := types.Response{Error: "revision 2 is no longer kept, the oldest one is 3"}
+	if resp := <-responder.responses; !reflect.DeepEqual(resp, expected) {
+		t.Errorf("Response = %+v, expected %+v", resp, expected)
+	}
+	close(reqs)
+	<-done
+}
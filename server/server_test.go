@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"server/logger"
+
+	mq "github.com/enriquenc/orderer-map-client-server-go/mq"
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+func TestPipeline_MemoryBroker(t *testing.T) {
+	// Create a temporary file for the logger
+	file, err := ioutil.TempFile("", "logger_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	myLogger, err := logger.NewLogger(file.Name())
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer myLogger.Close()
+
+	broker := mq.NewMemoryBroker()
+
+	done, err := startProcessing(broker, myLogger)
+	if err != nil {
+		t.Fatalf("Error starting processing: %v", err)
+	}
+	defer func() {
+		broker.Close()
+		<-done
+	}()
+
+	steps := []struct {
+		req      types.Request
+		expected types.Response
+	}{
+		{types.Request{Action: types.AddItem, Key: "a", Value: "1"}, types.Response{}},
+		{types.Request{Action: types.AddItem, Key: "b", Value: "2"}, types.Response{}},
+		{types.Request{Action: types.GetItem, Key: "a"}, types.Response{Found: true, Value: "1", Version: 1, Revision: 2}},
+		{types.Request{Action: types.RemoveItem, Key: "a"}, types.Response{Found: true}},
+		{types.Request{Action: types.RemoveItem, Key: "a"}, types.Response{Found: false}},
+		{types.Request{Action: types.AddItem, Key: "a", Value: "3"}, types.Response{}},
+		{types.Request{Action: types.GetAll}, types.Response{Items: types.Items{{Key: "b", Value: "2"}, {Key: "a", Value: "3"}}, Revision: 4}},
+	}
+
+	for i, step := range steps {
+		resp, err := broker.Call(step.req, time.Second)
+		if err != nil {
+			t.Fatalf("Step %d: call failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(resp, step.expected) {
+			t.Errorf("Step %d: response = %+v, expected %+v", i, resp, step.expected)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	orderermap "server/orderer-map"
+)
+
+// Version is the snapshot file format version.
+const Version = 1
+
+const snapshotExt = ".snap"
+
+// Snapshot is the full ordered content of the map after
+// the write-ahead log record Seq was applied.
+type Snapshot struct {
+	Seq uint64
+	// Revision is the revision of the map
+	Revision uint64
+	Items    orderermap.Entries[string, string]
+	// Expiries holds the expiry times of the items with TTL
+	Expiries map[string]time.Time
+	// Versions holds the versions of the items
+	Versions map[string]uint64
+}
+
+// header is the first line of the snapshot file. It is followed
+// by Count lines with one JSON encoded entry per line.
+type header struct {
+	Version  int
+	Seq      uint64
+	Revision uint64
+	Count    int
+}
+
+// entry is the snapshot line of an item.
+type entry struct {
+	Key       string
+	Value     string
+	ExpiresAt *time.Time `json:",omitempty"`
+	Version   uint64     `json:",omitempty"`
+}
+
+// Store keeps the snapshots in a directory. Every snapshot is a separate
+// file named after its sequence number, only the Keep latest ones are retained.
+type Store struct {
+	dir  string
+	keep int
+}
+
+func NewStore(dir string, keep int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+	if keep < 1 {
+		keep = 1
+	}
+	return &Store{dir: dir, keep: keep}, nil
+}
+
+// Save atomically writes the snapshot and removes the outdated ones.
+func (s *Store) Save(snap Snapshot) error {
+	tmp, err := os.CreateTemp(s.dir, "snapshot-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp, snap); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync snapshot file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(snap.Seq)); err != nil {
+		return fmt.Errorf("failed to rename snapshot file: %v", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	return s.prune()
+}
+
+// Latest loads the most recent readable snapshot. It returns nil if there is none.
+func (s *Store) Latest() (*Snapshot, error) {
+	seqs, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for i := len(seqs) - 1; i >= 0; i-- {
+		snap, err := load(s.path(seqs[i]))
+		if err == nil {
+			return snap, nil
+		}
+		// Fall back to the previous snapshot
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+func (s *Store) prune() error {
+	seqs, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(seqs)-s.keep; i++ {
+		if err := os.Remove(s.path(seqs[i])); err != nil {
+			return fmt.Errorf("failed to remove snapshot: %v", err)
+		}
+	}
+	return nil
+}
+
+// list returns the sequence numbers of the stored snapshots in ascending order.
+func (s *Store) list() ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %v", err)
+	}
+
+	var seqs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, snapshotExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	return seqs, nil
+}
+
+func (s *Store) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, snapshotExt))
+}
+
+func write(file *os.File, snap Snapshot) error {
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	if err := encoder.Encode(header{Version: Version, Seq: snap.Seq, Revision: snap.Revision, Count: len(snap.Items)}); err != nil {
+		return fmt.Errorf("failed to write snapshot header: %v", err)
+	}
+	for _, item := range snap.Items {
+		line := entry{Key: item.Key, Value: item.Value}
+		if expiresAt, ok := snap.Expiries[item.Key]; ok {
+			line.ExpiresAt = &expiresAt
+		}
+		line.Version = snap.Versions[item.Key]
+		if err := encoder.Encode(line); err != nil {
+			return fmt.Errorf("failed to write snapshot entry: %v", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %v", err)
+	}
+	return nil
+}
+
+func load(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+
+	var h header
+	if err := decoder.Decode(&h); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot header of %s: %v", filepath.Base(path), err)
+	}
+	if h.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d of %s", h.Version, filepath.Base(path))
+	}
+
+	snap := &Snapshot{Seq: h.Seq, Revision: h.Revision, Items: make(orderermap.Entries[string, string], 0, h.Count)}
+	for i := 0; i < h.Count; i++ {
+		var line entry
+		if err := decoder.Decode(&line); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot entry %d of %s: %v", i, filepath.Base(path), err)
+		}
+		snap.Items = append(snap.Items, orderermap.Entry[string, string]{Key: line.Key, Value: line.Value})
+		if line.ExpiresAt != nil {
+			if snap.Expiries == nil {
+				snap.Expiries = make(map[string]time.Time)
+			}
+			snap.Expiries[line.Key] = *line.ExpiresAt
+		}
+		if line.Version != 0 {
+			if snap.Versions == nil {
+				snap.Versions = make(map[string]uint64)
+			}
+			snap.Versions[line.Key] = line.Version
+		}
+	}
+
+	return snap, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot directory: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot directory: %v", err)
+	}
+	return nil
+}
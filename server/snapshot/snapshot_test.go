@@ -0,0 +1,110 @@
+package snapshot
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	orderermap "server/orderer-map"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_SaveAndLatest(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 2)
+	require.NoError(t, err)
+
+	// No snapshots yet
+	snap, err := store.Latest()
+	require.NoError(t, err)
+	assert.Nil(t, snap)
+
+	items := orderermap.Entries[string, string]{{Key: "b", Value: "2"}, {Key: "a", Value: "1"}}
+	require.NoError(t, store.Save(Snapshot{Seq: 5, Items: items}))
+	require.NoError(t, store.Save(Snapshot{Seq: 7, Items: items[:1]}))
+
+	snap, err = store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, &Snapshot{Seq: 7, Items: items[:1]}, snap)
+}
+
+func TestStore_Prune(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 2)
+	require.NoError(t, err)
+
+	for seq := uint64(1); seq <= 4; seq++ {
+		require.NoError(t, store.Save(Snapshot{Seq: seq}))
+	}
+
+	seqs, err := store.list()
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 4}, seqs)
+}
+
+func TestStore_LatestFallsBackOnBrokenSnapshot(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 2)
+	require.NoError(t, err)
+
+	items := orderermap.Entries[string, string]{{Key: "a", Value: "1"}}
+	require.NoError(t, store.Save(Snapshot{Seq: 1, Items: items}))
+	require.NoError(t, store.Save(Snapshot{Seq: 2, Items: items}))
+
+	// Cut the entries of the latest snapshot
+	require.NoError(t, os.Truncate(store.path(2), 40))
+
+	snap, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), snap.Seq)
+}
+
+func TestStore_Expiries(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 1)
+	require.NoError(t, err)
+
+	expiresAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	items := orderermap.Entries[string, string]{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	expiries := map[string]time.Time{"b": expiresAt}
+	require.NoError(t, store.Save(Snapshot{Seq: 1, Items: items, Expiries: expiries}))
+
+	snap, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, &Snapshot{Seq: 1, Items: items, Expiries: expiries}, snap)
+}
+
+func TestStore_Versions(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 1)
+	require.NoError(t, err)
+
+	items := orderermap.Entries[string, string]{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	versions := map[string]uint64{"a": 1, "b": 5}
+	require.NoError(t, store.Save(Snapshot{Seq: 1, Revision: 7, Items: items, Versions: versions}))
+
+	snap, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, &Snapshot{Seq: 1, Revision: 7, Items: items, Versions: versions}, snap)
+}
+
+func TestStore_Version1(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 1)
+	require.NoError(t, err)
+
+	content := `{"Version":1,"Seq":3,"Count":1}` + "\n" + `{"Key":"a","Value":"1"}` + "\n"
+	require.NoError(t, os.WriteFile(store.path(3), []byte(content), 0644))
+
+	snap, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, &Snapshot{Seq: 3, Items: orderermap.Entries[string, string]{{Key: "a", Value: "1"}}}, snap)
+}
+
+func TestStore_UnsupportedVersion(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 1)
+	require.NoError(t, err)
+
+	err = os.WriteFile(store.path(1), []byte(`{"Version":99,"Seq":1,"Count":0}`+"\n"), 0644)
+	require.NoError(t, err)
+
+	_, err = store.Latest()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported snapshot version 99")
+}
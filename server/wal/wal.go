@@ -0,0 +1,452 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+)
+
+const segmentExt = ".wal"
+
+// SyncPolicy defines when the appended records are flushed to the disk.
+type SyncPolicy string
+
+const (
+	// SyncAlways flushes every record before Append returns.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval flushes the records in background once per SyncInterval.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+func ParseSyncPolicy(policy string) (SyncPolicy, error) {
+	switch SyncPolicy(policy) {
+	case SyncAlways, SyncInterval, SyncNever:
+		return SyncPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown sync policy %q. Must be one of: %s, %s, %s", policy, SyncAlways, SyncInterval, SyncNever)
+	}
+}
+
+type Options struct {
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	// SegmentSize is the size after which the log switches to a new segment file
+	SegmentSize int64
+}
+
+var DefaultOptions = Options{
+	Sync:         SyncAlways,
+	SyncInterval: time.Second,
+	SegmentSize:  64 << 20,
+}
+
+// Record is a mutation applied to the map. Seq is the position
+// of the record in the log starting from 1.
+type Record struct {
+	Seq     uint64
+	Request types.Request
+}
+
+// Log is an append-only write-ahead log of the map mutations.
+// It is stored as a sequence of segment files with one JSON encoded record per line,
+// every segment is named after the sequence number of its first record.
+type Log struct {
+	dir  string
+	opts Options
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	lastSeq  uint64
+	segments []uint64
+	dirty    bool
+	// broken is set when a failed write can't be undone, the log rejects further records
+	broken error
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open opens the log stored in dir, creating the directory if needed.
+// A partially written record at the end of the log, left by a crash, is truncated.
+func Open(dir string, opts Options) (*Log, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		dir:      dir,
+		opts:     opts,
+		segments: segments,
+		done:     make(chan struct{}),
+	}
+
+	if len(segments) == 0 {
+		if err := l.openSegment(1); err != nil {
+			return nil, err
+		}
+	} else {
+		// Find the last sequence number and repair the tail of the last segment
+		last := segments[len(segments)-1]
+		end, lastSeq, err := scanSegment(l.segmentPath(last), last, nil, true)
+		if err != nil {
+			return nil, err
+		}
+		if lastSeq == 0 {
+			lastSeq = last - 1
+		}
+
+		file, err := os.OpenFile(l.segmentPath(last), os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log segment: %v", err)
+		}
+		if err := file.Truncate(end); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to truncate log segment: %v", err)
+		}
+		if _, err := file.Seek(end, io.SeekStart); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to seek log segment: %v", err)
+		}
+		l.file = file
+		l.size = end
+		l.lastSeq = lastSeq
+	}
+
+	if opts.Sync == SyncInterval {
+		l.wg.Add(1)
+		go l.syncLoop()
+	}
+
+	return l, nil
+}
+
+// FirstSeq returns the sequence number of the first record kept in the log.
+func (l *Log) FirstSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.segments[0]
+}
+
+// LastSeq returns the sequence number of the last record in the log.
+func (l *Log) LastSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lastSeq
+}
+
+// Append writes the request to the end of the log and returns its sequence number.
+// A record which fails to be written or flushed is removed from the log, so it's never
+// recovered. Once the record is on the disk the append succeeds, a failed switch to
+// a new segment is retried by the next append.
+func (l *Log) Append(req types.Request) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return 0, errors.New("log is closed")
+	}
+	if l.broken != nil {
+		return 0, l.broken
+	}
+
+	rec := Record{Seq: l.lastSeq + 1, Request: req}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode record: %v", err)
+	}
+	line = append(line, '\n')
+
+	offset := l.size
+	if _, err := l.file.Write(line); err != nil {
+		return 0, l.undoLocked(offset, fmt.Errorf("failed to write record: %v", err))
+	}
+	l.size += int64(len(line))
+	l.dirty = true
+
+	if l.opts.Sync == SyncAlways {
+		if err := l.syncLocked(); err != nil {
+			return 0, l.undoLocked(offset, err)
+		}
+	}
+	l.lastSeq = rec.Seq
+
+	if l.opts.SegmentSize > 0 && l.size >= l.opts.SegmentSize {
+		if err := l.rollLocked(); err != nil {
+			log.Printf("WAL: %v", err)
+		}
+	}
+
+	return rec.Seq, nil
+}
+
+// undoLocked truncates the segment back to the offset the failed record was written at,
+// so the following records aren't written after its torn bytes. If it fails the log is
+// broken and rejects all the following records.
+func (l *Log) undoLocked(offset int64, cause error) error {
+	l.size = offset
+	if err := l.file.Truncate(offset); err != nil {
+		l.broken = fmt.Errorf("log is broken by the failed record (%v): failed to truncate log segment: %v", cause, err)
+		return l.broken
+	}
+	if _, err := l.file.Seek(offset, io.SeekStart); err != nil {
+		l.broken = fmt.Errorf("log is broken by the failed record (%v): failed to seek log segment: %v", cause, err)
+		return l.broken
+	}
+	if err := l.file.Sync(); err != nil {
+		l.broken = fmt.Errorf("log is broken by the failed record (%v): failed to sync log segment: %v", cause, err)
+		return l.broken
+	}
+	l.dirty = false
+	return cause
+}
+
+// Replay calls fn for every record with the sequence number greater than after, in order.
+func (l *Log) Replay(after uint64, fn func(Record) error) error {
+	l.mu.Lock()
+	segments := append([]uint64(nil), l.segments...)
+	lastSeq := l.lastSeq
+	l.mu.Unlock()
+
+	if lastSeq > after && segments[0] > after+1 {
+		return fmt.Errorf("records %d-%d are missing from the log", after+1, segments[0]-1)
+	}
+
+	for i, first := range segments {
+		// Skip the segments which end before the requested position
+		if i+1 < len(segments) && segments[i+1] <= after+1 {
+			continue
+		}
+		_, _, err := scanSegment(l.segmentPath(first), first, func(rec Record) error {
+			if rec.Seq <= after {
+				return nil
+			}
+			return fn(rec)
+		}, i == len(segments)-1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Truncate removes the segments which contain only the records with
+// the sequence number up to seq, e.g. covered by a snapshot.
+// The segment being written is never removed.
+func (l *Log) Truncate(seq uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The removed segments are forgotten one by one, so a failure leaves the kept ones listed
+	for len(l.segments) > 1 && l.segments[1] <= seq+1 {
+		if err := os.Remove(l.segmentPath(l.segments[0])); err != nil {
+			return fmt.Errorf("failed to remove log segment: %v", err)
+		}
+		l.segments = l.segments[1:]
+	}
+
+	return nil
+}
+
+// Sync flushes the written records to the disk.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.syncLocked()
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.file == nil {
+		l.mu.Unlock()
+		return errors.New("log is already closed")
+	}
+	close(l.done)
+	l.mu.Unlock()
+
+	l.wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.syncLocked()
+	if closeErr := l.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close log segment: %v", closeErr)
+	}
+	l.file = nil
+
+	return err
+}
+
+func (l *Log) syncLoop() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.Sync(); err != nil {
+				log.Printf("WAL: %v", err)
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+func (l *Log) syncLocked() error {
+	if !l.dirty || l.file == nil {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log segment: %v", err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// rollLocked starts the next segment and closes the current one.
+// The current segment is kept if the next one can't be created.
+func (l *Log) rollLocked() error {
+	if err := l.syncLocked(); err != nil {
+		return err
+	}
+
+	current := l.file
+	if err := l.openSegment(l.lastSeq + 1); err != nil {
+		return err
+	}
+	if err := current.Close(); err != nil {
+		return fmt.Errorf("failed to close log segment: %v", err)
+	}
+	return nil
+}
+
+func (l *Log) openSegment(first uint64) error {
+	file, err := os.OpenFile(l.segmentPath(first), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create log segment: %v", err)
+	}
+	if err := syncDir(l.dir); err != nil {
+		// The segment named after the next record must not be left for the retry
+		file.Close()
+		os.Remove(l.segmentPath(first))
+		return err
+	}
+
+	l.file = file
+	l.size = 0
+	l.segments = append(l.segments, first)
+	return nil
+}
+
+func (l *Log) segmentPath(first uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", first, segmentExt))
+}
+
+// listSegments returns the first sequence numbers of the segments in dir in ascending order.
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory: %v", err)
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, first)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+
+	return segments, nil
+}
+
+// scanSegment reads the records of the segment and checks that they are sequential.
+// It returns the size of the valid part of the segment and the last sequence number.
+// If the segment is the last one a broken record at its end is treated as a torn
+// write and ignored, otherwise it is an error.
+func scanSegment(path string, first uint64, fn func(Record) error, last bool) (int64, uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open log segment: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	var lastSeq uint64
+	expected := first
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return offset, lastSeq, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, 0, fmt.Errorf("failed to read log segment: %v", err)
+		}
+
+		var rec Record
+		if err == io.EOF || json.Unmarshal(line, &rec) != nil || rec.Seq != expected {
+			if last {
+				return offset, lastSeq, nil
+			}
+			return 0, 0, fmt.Errorf("log segment %s is corrupted at offset %d", filepath.Base(path), offset)
+		}
+
+		if fn != nil {
+			if err := fn(rec); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		offset += int64(len(line))
+		lastSeq = rec.Seq
+		expected++
+	}
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open log directory: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log directory: %v", err)
+	}
+	return nil
+}
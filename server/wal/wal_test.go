@@ -0,0 +1,217 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	types "github.com/enriquenc/orderer-map-client-server-go/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(t *testing.T, l *Log, after uint64) []Record {
+	var records []Record
+	err := l.Replay(after, func(rec Record) error {
+		records = append(records, rec)
+		return nil
+	})
+	require.NoError(t, err)
+	return records
+}
+
+func TestLog_AppendAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, DefaultOptions)
+	require.NoError(t, err)
+
+	seq, err := l.Append(types.Request{Action: types.AddItem, Key: "a", Value: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+	seq, err = l.Append(types.Request{Action: types.RemoveItem, Key: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+
+	assert.Equal(t, []Record{
+		{Seq: 1, Request: types.Request{Action: types.AddItem, Key: "a", Value: "1"}},
+		{Seq: 2, Request: types.Request{Action: types.RemoveItem, Key: "a"}},
+	}, collect(t, l, 0))
+	assert.Len(t, collect(t, l, 1), 1)
+
+	require.NoError(t, l.Close())
+	assert.Error(t, l.Close())
+}
+
+func TestLog_ReopenContinuesSequence(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, DefaultOptions)
+	require.NoError(t, err)
+	_, err = l.Append(types.Request{Action: types.AddItem, Key: "a", Value: "1"})
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir, DefaultOptions)
+	require.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, uint64(1), l.LastSeq())
+	seq, err := l.Append(types.Request{Action: types.AddItem, Key: "b", Value: "2"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+	assert.Len(t, collect(t, l, 0), 2)
+}
+
+func TestLog_TruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{Sync: SyncNever})
+	require.NoError(t, err)
+	_, err = l.Append(types.Request{Action: types.AddItem, Key: "a", Value: "1"})
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	// Simulate a crash in the middle of writing a record
+	file, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%020d%s", 1, segmentExt)), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"Seq":2,"Request":{"Act`)
+	require.NoError(t, err)
+	file.Close()
+
+	l, err = Open(dir, Options{Sync: SyncNever})
+	require.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, uint64(1), l.LastSeq())
+	seq, err := l.Append(types.Request{Action: types.AddItem, Key: "b", Value: "2"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+
+	records := collect(t, l, 0)
+	require.Len(t, records, 2)
+	assert.Equal(t, "b", records[1].Request.Key)
+}
+
+func TestLog_BrokenByFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, DefaultOptions)
+	require.NoError(t, err)
+	_, err = l.Append(types.Request{Action: types.AddItem, Key: "a", Value: "1"})
+	require.NoError(t, err)
+
+	// The read-only segment can be neither written nor truncated
+	segment := l.file
+	l.file, err = os.Open(segment.Name())
+	require.NoError(t, err)
+	_, err = l.Append(types.Request{Action: types.AddItem, Key: "b", Value: "2"})
+	assert.Error(t, err)
+	l.file.Close()
+	l.file = segment
+
+	// The following records are rejected even if they could be written
+	_, err = l.Append(types.Request{Action: types.AddItem, Key: "c", Value: "3"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "log is broken")
+	assert.Equal(t, uint64(1), l.LastSeq())
+	require.NoError(t, l.Close())
+
+	l, err = Open(dir, DefaultOptions)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, []Record{{Seq: 1, Request: types.Request{Action: types.AddItem, Key: "a", Value: "1"}}}, collect(t, l, 0))
+}
+
+func TestLog_Segments(t *testing.T) {
+	dir := t.TempDir()
+
+	// Every record goes to its own segment
+	l, err := Open(dir, Options{Sync: SyncInterval, SyncInterval: DefaultOptions.SyncInterval, SegmentSize: 1})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err := l.Append(types.Request{Action: types.AddItem, Key: fmt.Sprintf("k%d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, l.Close())
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, segments)
+
+	l, err = Open(dir, DefaultOptions)
+	require.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, uint64(5), l.LastSeq())
+	records := collect(t, l, 3)
+	require.Len(t, records, 2)
+	assert.Equal(t, uint64(4), records[0].Seq)
+	assert.Equal(t, "k4", records[1].Request.Key)
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	for _, policy := range []string{"always", "interval", "never"} {
+		parsed, err := ParseSyncPolicy(policy)
+		assert.NoError(t, err)
+		assert.Equal(t, SyncPolicy(policy), parsed)
+	}
+
+	_, err := ParseSyncPolicy("sometimes")
+	assert.Error(t, err)
+}
+
+func TestLog_Truncate(t *testing.T) {
+	dir := t.TempDir()
+
+	// Every record goes to its own segment
+	l, err := Open(dir, Options{Sync: SyncNever, SegmentSize: 1})
+	require.NoError(t, err)
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := l.Append(types.Request{Action: types.AddItem, Key: fmt.Sprintf("k%d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, l.Truncate(3))
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5, 6}, segments)
+
+	records := collect(t, l, 3)
+	require.Len(t, records, 2)
+	assert.Equal(t, uint64(4), records[0].Seq)
+
+	// The truncated records can't be replayed anymore
+	err = l.Replay(0, func(Record) error { return nil })
+	assert.Error(t, err)
+
+	// The segment being written is kept
+	require.NoError(t, l.Truncate(10))
+	segments, err = listSegments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{6}, segments)
+}
+
+func TestLog_TruncateFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, Options{Sync: SyncNever, SegmentSize: 1})
+	require.NoError(t, err)
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := l.Append(types.Request{Action: types.AddItem, Key: fmt.Sprintf("k%d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+
+	// The second segment can't be removed, the first one is
+	require.NoError(t, os.Remove(l.segmentPath(2)))
+	require.NoError(t, os.Mkdir(l.segmentPath(2), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(l.segmentPath(2), "file"), nil, 0644))
+	assert.Error(t, l.Truncate(3))
+	assert.Equal(t, uint64(2), l.segments[0])
+}
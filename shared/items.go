@@ -0,0 +1,140 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Item is a key/value pair of the ordered map.
+type Item struct {
+	Key   string
+	Value string
+}
+
+// Items is an ordered list of the map items. It is encoded to JSON as an object
+// which keeps the order of the keys, the same way ExpectedResponse is generated.
+type Items []Item
+
+func (items Items) MarshalJSON() ([]byte, error) {
+	return MarshalEntries(items)
+}
+
+func (items *Items) UnmarshalJSON(data []byte) error {
+	return UnmarshalEntries((*[]Item)(items), data)
+}
+
+// MarshalEntries encodes the key/value pairs as a JSON object which keeps their order,
+// e.g. {"key2":"value2","key1":"value1"}. Keys encoded as JSON strings are used
+// as is, numbers and booleans are quoted.
+func MarshalEntries[K comparable, V any, E ~struct {
+	Key   K
+	Value V
+}](entries []E) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, e := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		entry := struct {
+			Key   K
+			Value V
+		}(e)
+
+		key, err := marshalKey(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalEntries decodes the JSON object encoded by MarshalEntries keeping the order of its keys.
+func UnmarshalEntries[K comparable, V any, E ~struct {
+	Key   K
+	Value V
+}](entries *[]E, data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		*entries = nil
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("expected JSON object, got %v", token)
+	}
+
+	result := []E{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		var entry struct {
+			Key   K
+			Value V
+		}
+		if err := unmarshalKey(token.(string), &entry.Key); err != nil {
+			return err
+		}
+		if err := decoder.Decode(&entry.Value); err != nil {
+			return err
+		}
+		result = append(result, E(entry))
+	}
+
+	// Consume the closing delimiter
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	*entries = result
+	return nil
+}
+
+func marshalKey(key any) ([]byte, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b[0] {
+	case '"':
+		return b, nil
+	case '{', '[', 'n':
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	default:
+		return []byte(strconv.Quote(string(b))), nil
+	}
+}
+
+func unmarshalKey(text string, key any) error {
+	// String and encoding.TextUnmarshaler keys
+	if err := json.Unmarshal([]byte(strconv.Quote(text)), key); err == nil {
+		return nil
+	}
+	// Number and boolean keys
+	if err := json.Unmarshal([]byte(text), key); err != nil {
+		return fmt.Errorf("failed to decode key %q: %v", text, err)
+	}
+	return nil
+}
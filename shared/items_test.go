@@ -0,0 +1,38 @@
+package shared
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestItems_JSONKeepsOrder(t *testing.T) {
+	items := Items{{Key: "key8", Value: "value8"}, {Key: "a=b", Value: "1"}, {Key: "key3", Value: "value3"}}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		t.Fatalf("Failed to encode items: %v", err)
+	}
+	expected := `{"key8":"value8","a=b":"1","key3":"value3"}`
+	if string(b) != expected {
+		t.Errorf("json.Marshal(items) = %s, expected %s", b, expected)
+	}
+
+	var decoded Items
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Failed to decode items: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, items) {
+		t.Errorf("json.Unmarshal() = %v, expected %v", decoded, items)
+	}
+}
+
+func TestItems_UnmarshalJSONInvalid(t *testing.T) {
+	var items Items
+	if err := json.Unmarshal([]byte(`["key8"]`), &items); err == nil {
+		t.Errorf("Expected error when decoding JSON array, but got nil")
+	}
+	if err := json.Unmarshal([]byte(`{"key8":1}`), &items); err == nil {
+		t.Errorf("Expected error when decoding non-string value, but got nil")
+	}
+}
@@ -1,19 +1,130 @@
 package shared
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
 const (
 	AddItem    string = "add"
 	RemoveItem string = "remove"
 	GetItem    string = "get"
 	GetAll     string = "getAll"
+
+	// Positional actions: insert the item next to the Mark item or move the item to an end
+	InsertBefore string = "insertBefore"
+	InsertAfter  string = "insertAfter"
+	MoveToFront  string = "moveToFront"
+	MoveToBack   string = "moveToBack"
+
+	// Paginated reads: a page by Offset or After cursor and Limit, the first or the last Limit items
+	GetPage string = "getPage"
+	GetHead string = "getHead"
+	GetTail string = "getTail"
+
+	// Positional reads: the item at the Offset position and the position of the key
+	GetAt   string = "getAt"
+	IndexOf string = "indexOf"
+
+	// Conditional writes: change the item only if it's absent, present
+	// or has the Expected value and the Version
+	AddIfAbsent     string = "addIfAbsent"
+	UpdateIfPresent string = "updateIfPresent"
+	CompareAndSwap  string = "compareAndSwap"
+	RemoveIfValue   string = "removeIfValue"
+
+	// Batch applies the Ops mutations atomically: all of them or none
+	Batch string = "batch"
 )
 
 type Request struct {
+	// ID is the unique request identifier used by the server to detect
+	// duplicated deliveries. Requests without ID are never deduplicated.
+	ID     string `json:",omitempty"`
 	Action string
 	Key    string
 	Value  string
+	// Mark is the key of the item to insert the new one before or after
+	Mark string `json:",omitempty"`
+	// Offset is the number of the items skipped before the page (getPage)
+	// or the position of the item (getAt)
+	Offset int `json:",omitempty"`
+	// Limit is the maximum number of the items returned (getPage, getHead, getTail)
+	Limit int `json:",omitempty"`
+	// After is the cursor key, the page starts right after it (getPage)
+	After string `json:",omitempty"`
+	// Reverse pages from the tail to the head (getPage)
+	Reverse bool `json:",omitempty"`
+	// TTL makes the added item expire after the duration, zero means never
+	TTL time.Duration `json:",omitempty"`
+	// ExpiresAt is the expiry time of the added item set by the server from TTL,
+	// so the item expires at the same time after it's recovered from the log.
+	// The value sent by the client is ignored
+	ExpiresAt *time.Time `json:",omitempty"`
+	// Expected is the value the item must have to be changed (compareAndSwap, removeIfValue)
+	Expected string `json:",omitempty"`
+	// Version is the version the item must have to be changed (compareAndSwap, removeIfValue)
+	Version uint64 `json:",omitempty"`
+	// AppliedVersion is the version the conditional write gave to the item, set by the server
+	// when the write is persisted, so the duplicated delivery after a restart is answered
+	// with it. The value sent by the client is ignored
+	AppliedVersion uint64 `json:",omitempty"`
+	// Revision is the past revision of the map to read the items at, zero for the current one
+	// (get, getAll, getPage, getHead, getTail)
+	Revision uint64 `json:",omitempty"`
+	// Ops are the mutations applied in order by the batch (batch)
+	Ops []Request `json:",omitempty"`
+
+	// ReplyTo, CorrelationID and DeliveryTag are taken from the message
+	// properties, they are not a part of the message body.
+	ReplyTo       string `json:"-"`
+	CorrelationID string `json:"-"`
+	DeliveryTag   uint64 `json:"-"`
+}
+
+// NewRequestID generates a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Response is the answer of the server to a processed request.
+type Response struct {
+	// Found reports whether the requested key exists (get, remove, moveToFront, moveToBack, indexOf,
+	// conditional writes), the mark key exists (insertBefore, insertAfter) or the position is in range (getAt)
+	Found bool
+	// Value is the value of the item (get, getAt) or the current one the conditional write conflicted with
+	Value string
+	// Version is the version of the item (get) or of the changed or the conflicting one (conditional writes)
+	Version uint64 `json:",omitempty"`
+	// Revision is the revision of the map the items were read at, pass it with the following
+	// page requests to read them consistently (get, getAll, getPage, getHead, getTail)
+	Revision uint64 `json:",omitempty"`
+	// Conflict reports that the condition of the conditional write didn't hold and nothing was changed
+	Conflict bool `json:",omitempty"`
+	// Key is the key of the item at the position (getAt)
+	Key string `json:",omitempty"`
+	// Index is the position of the key in insertion order (indexOf)
+	Index int `json:",omitempty"`
+	// Items holds all the map items in insertion order (getAll) or the page of them
+	// in the iteration order (getPage, getHead, getTail)
+	Items Items `json:",omitempty"`
+	// Next is the cursor to request the following page with, empty if the page
+	// is the last one (getPage, getHead)
+	Next string `json:",omitempty"`
+	// Results are the responses to the operations of the applied batch in their order (batch)
+	Results []Response `json:",omitempty"`
+	Error   string
 }
 
 type TestDataAction struct {
-	RequestData      Request
-	ExpectedResponse interface{}
+	RequestData Request
+	// ExpectedResponse is the JSON encoded expected result of the action:
+	// the value (or null) for get and the ordered items object for getAll
+	ExpectedResponse json.RawMessage
 }
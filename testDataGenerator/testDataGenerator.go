@@ -72,7 +72,12 @@ func main() {
 			}
 		}
 
-		testData = append(testData, types.TestDataAction{RequestData: types.Request{Action: action, Key: key, Value: value}, ExpectedResponse: expectedResponse})
+		expectedResponseData, err := json.Marshal(expectedResponse)
+		if err != nil {
+			log.Fatalf("error encoding expected response: %v", err)
+		}
+
+		testData = append(testData, types.TestDataAction{RequestData: types.Request{Action: action, Key: key, Value: value}, ExpectedResponse: expectedResponseData})
 	}
 
 	encoder := json.NewEncoder(file)